@@ -29,6 +29,7 @@ func main() {
 
 func readFromServer(conn net.Conn) {
 	reader := bufio.NewReader(conn)
+	progressing := false // a progress line is being rewritten
 	for {
 		message, err := reader.ReadString('\n')
 		if err != nil {
@@ -48,7 +49,22 @@ func readFromServer(conn net.Conn) {
 				continue
 			}
 			receiveImage(reader, size)
+		} else if strings.HasPrefix(message, "PREVIEW:") {
+			// the next image is an intermediate pass of the render, replaced by the following ones
+			if progressing {
+				fmt.Println()
+				progressing = false
+			}
+			fmt.Printf("Preview %s:\n", strings.TrimPrefix(message, "PREVIEW:"))
+		} else if strings.HasPrefix(message, "PROGRESS:") {
+			// rewrites the same line until the render ends
+			fmt.Printf("\rRendering: %-60s", strings.TrimPrefix(message, "PROGRESS:"))
+			progressing = true
 		} else {
+			if progressing {
+				fmt.Println()
+				progressing = false
+			}
 			fmt.Println(message)
 		}
 	}
@@ -1,39 +1,178 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
 	. "mandelbrot/mandelbrot"
+	"os"
+	"os/signal"
+	"strconv"
 	"time"
 )
 
 func main() {
+	juliaFlag := flag.String("julia", "", "render the Julia set of this constant (e.g. -0.8+0.156i) instead of the Mandelbrot set")
+	formulaFlag := flag.String("formula", "mandelbrot", "formula to iterate: mandelbrot, burningship, tricorn or multibrot:<power>")
+	kernelFlag := flag.String("kernel", "reference", "escape time loop: reference (float64), fast, batch, float32 or fixed")
+	strategyFlag := flag.String("strategy", "bruteforce", "pixels to iterate: bruteforce (all) or subdivision (Mariani-Silver, may miss thin details)")
+	coloringFlag := flag.String("coloring", "banded", "coloring of the escape count: banded or smooth")
+	paletteFlag := flag.String("palette", "classic", "built-in palette: classic, grayscale, fire or ultra")
+	paletteFileFlag := flag.String("palette-file", "", "JSON gradient file to use instead of a built-in palette")
+	paletteOffsetFlag := flag.Float64("palette-offset", 0, "escape value added before mapping to the palette")
+	paletteScaleFlag := flag.Float64("palette-scale", 0, "escape values spanned by one pass of the palette (0 keeps the palette's own)")
+	samplesFlag := flag.Int("samples", 1, "supersample every pixel with n x n samples")
+	samplingFlag := flag.String("sampling", "grid", "placement of the samples: grid or jittered")
+	adaptiveFlag := flag.Bool("adaptive", false, "only supersample the pixels on an edge of the escape values")
+	thresholdFlag := flag.Float64("threshold", DefaultAdaptiveThreshold, "escape value difference between neighbors that makes an edge, 0 for any difference")
+	boundsFlag := flag.String("bounds", "", "corners xmin,xmax,ymin,ymax of the view")
+	centerFlag := flag.String("center", "", "center x,y of the view, in decimal (needs -scale or -zoom)")
+	scaleFlag := flag.String("scale", "", "width of the view in the complex plane, in decimal")
+	zoomFlag := flag.Float64("zoom", 0, "magnification from the default view of the formula, instead of -scale")
+	angleFlag := flag.Float64("angle", 0, "rotation of the view around its center, in degrees")
+	skewFlag := flag.Float64("skew", 0, "horizontal shear of the view, applied before the rotation")
+	precisionFlag := flag.Uint("precision", 0, "mantissa size in bits of the deep zoom numbers, 0 renders in float64")
+	exactFlag := flag.Bool("exact", false, "iterate every pixel of the deep zoom in arbitrary precision instead of by perturbation")
+	timeoutFlag := flag.Duration("timeout", 0, "stop the render after this duration and save the pixels computed so far, 0 never stops")
+	workersFlag := flag.Int("workers", 0, "number of goroutines sharing the tiles of the image, 0 uses every CPU")
+	flag.Parse()
+
+	formula, err := FormulaByName(*formulaFlag)
+	if err != nil {
+		fmt.Println("Error parsing formula:", err)
+		return
+	}
+	kernel, err := KernelByName(*kernelFlag)
+	if err != nil {
+		fmt.Println("Error parsing kernel:", err)
+		return
+	}
+	strategy, err := StrategyByName(*strategyFlag)
+	if err != nil {
+		fmt.Println("Error parsing strategy:", err)
+		return
+	}
+	coloring, err := ColoringByName(*coloringFlag)
+	if err != nil {
+		fmt.Println("Error parsing coloring:", err)
+		return
+	}
+	palette, err := PaletteByName(*paletteFlag)
+	if *paletteFileFlag != "" {
+		palette, err = LoadPalette(*paletteFileFlag)
+	}
+	if err != nil {
+		fmt.Println("Error loading palette:", err)
+		return
+	}
+	palette.Offset += *paletteOffsetFlag
+	if *paletteScaleFlag != 0 {
+		palette.Scale = *paletteScaleFlag
+	}
+	sampling, err := SamplingByName(*samplingFlag)
+	if err != nil {
+		fmt.Println("Error parsing sampling:", err)
+		return
+	}
+
 	// Define image dimensions
 	const width, height = 3840, 2160
-	const numGoRoutines = 10
 	const nbIteration = 10000
 
-	mandelbrot := NewMandelbrot(width, height)
+	mandelbrot := NewFractal(width, height, formula)
 	/*
 		mandelbrot.XMin = -1
 		mandelbrot.XMax = 0.5
 		mandelbrot.YMin = -0.75
 		mandelbrot.YMax = 0.75
 	*/
+	if *juliaFlag != "" {
+		c, err := strconv.ParseComplex(*juliaFlag, 128)
+		if err != nil {
+			fmt.Println("Error parsing Julia constant:", err)
+			return
+		}
+		mandelbrot = NewJulia(width, height, c)
+		mandelbrot.Formula = formula
+	}
+	mandelbrot.Kernel = kernel
+	mandelbrot.Strategy = strategy
+	mandelbrot.Coloring = coloring
+	mandelbrot.Palette = palette
+	mandelbrot.Samples = *samplesFlag
+	mandelbrot.Sampling = sampling
+	mandelbrot.Adaptive = *adaptiveFlag
+	mandelbrot.AdaptiveThreshold = *thresholdFlag
 
+	mandelbrot.Angle = *angleFlag
+	mandelbrot.Skew = *skewFlag
+
+	if *centerFlag == "" && (*scaleFlag != "" || *zoomFlag != 0 || *precisionFlag != 0 || *exactFlag) {
+		fmt.Println("Error parsing view: -scale, -zoom, -precision and -exact need -center")
+		return
+	}
+	if *centerFlag != "" && *boundsFlag != "" {
+		fmt.Println("Error parsing view: -bounds and -center cannot be used together")
+		return
+	}
+	if *boundsFlag != "" {
+		var xmin, xmax, ymin, ymax float64
+		_, err := fmt.Sscanf(*boundsFlag, "%g,%g,%g,%g", &xmin, &xmax, &ymin, &ymax)
+		if err != nil {
+			fmt.Println("Error parsing bounds:", err)
+			return
+		}
+		mandelbrot.XMin, mandelbrot.XMax, mandelbrot.YMin, mandelbrot.YMax = xmin, xmax, ymin, ymax
+	}
+	if *centerFlag != "" {
+		scale := *scaleFlag
+		if *zoomFlag != 0 {
+			scale = strconv.FormatFloat(mandelbrot.ZoomScale(*zoomFlag), 'g', -1, 64)
+		}
+		err := mandelbrot.SetCenter(*centerFlag, scale, *precisionFlag, *exactFlag)
+		if err != nil {
+			fmt.Println("Error parsing view:", err)
+			return
+		}
+	}
+
+	// Ctrl+C or the timeout stop the render, what is already computed is still saved
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if *timeoutFlag > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *timeoutFlag)
+		defer cancel()
+	}
+
+	// rewrites the same progress line until the render ends
+	mandelbrot.OnProgress = func(p Progress) {
+		fmt.Printf("\r%-60s", p)
+	}
+
+	numWorkers := Workers(*workersFlag)
 	start := time.Now()
-	fileName := fmt.Sprintf("Mandelbrot_image_(%dx%d)_with_%dgoroutines.png.png", width, height, numGoRoutines)
-	err := PrintOnImage(mandelbrot, fileName, numGoRoutines, nbIteration)
+	img, stats, err := Render(ctx, mandelbrot, numWorkers, nbIteration)
 	elapsed := time.Since(start)
+	fmt.Println()
 
-	if err != nil {
+	if err != nil && ctx.Err() == nil {
 		fmt.Println("Error generating Mandelbrot image:", err)
 		return
 	}
+	if err != nil {
+		fmt.Printf("Render stopped after %v (%v), saving the partial image\n", elapsed, err)
+	}
+	if mandelbrot.Samples > 1 {
+		fmt.Printf("Supersampled %d of %d pixels\n", stats.Refined, width*height)
+	}
 
 	// Save the image with a name based on dimensions
+	fileName := fmt.Sprintf("Mandelbrot_image_(%dx%d)_with_%dgoroutines.png.png", width, height, numWorkers)
+	err = SaveImage(img, fileName)
 	if err != nil {
 		fmt.Println("Error saving image:", err)
 	} else {
-		fmt.Printf("Mandelbrot_image_(%dx%d)_%v_with_%dgoroutines!\n", width, height, elapsed, numGoRoutines)
+		fmt.Printf("Mandelbrot_image_(%dx%d)_%v_with_%dgoroutines!\n", width, height, elapsed, numWorkers)
 	}
 }
@@ -0,0 +1,174 @@
+package mandelbrot
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"strings"
+	"sync/atomic"
+)
+
+// Sampling selects where the samples of a supersampled pixel are taken.
+type Sampling int
+
+const (
+	// Grid takes the samples at the centers of a regular grid over the pixel.
+	Grid Sampling = iota
+	// Jittered moves each sample randomly inside its cell of the grid (stratified sampling),
+	// which turns moiré into noise. The jitter only depends on the pixel and the sample,
+	// so renders stay reproducible.
+	Jittered
+)
+
+func (s Sampling) String() string {
+	switch s {
+	case Grid:
+		return "grid"
+	case Jittered:
+		return "jittered"
+	}
+	return fmt.Sprintf("Sampling(%d)", int(s))
+}
+
+// SamplingByName returns the sampling called name (grid or jittered).
+func SamplingByName(name string) (Sampling, error) {
+	switch strings.ToLower(name) {
+	case "", "grid":
+		return Grid, nil
+	case "jittered":
+		return Jittered, nil
+	}
+	return Grid, fmt.Errorf("unknown sampling %q", name)
+}
+
+// supersample recomputes the pixels of img selected by mask (all of them when mask is nil)
+// with m.Samples*m.Samples samples each, using parallel processing.
+// It returns the number of recomputed pixels, and stops between two rows of pixels when ctx is done.
+func supersample(ctx context.Context, img *image.RGBA, m Mandelbrot, numWorkers, nbIterations int, mask []bool) (int, error) {
+	/*
+		cuts the image into tiles like compute,
+		each worker writes the pixels of its tiles straight into the image
+	*/
+	var refined atomic.Int64
+	tiles := Tiles(m.Width, m.Height, TileSize)
+	progress := newProgressTracker(m.OnProgress, "supersampling", len(tiles))
+	err := runTiles(ctx, tiles, numWorkers, func(ctx context.Context, t Tile) error {
+		count := 0
+		defer func() { refined.Add(int64(count)) }()
+		for y := t.Y0; y < t.Y1; y++ {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			for x := t.X0; x < t.X1; x++ {
+				if mask == nil || mask[y*m.Width+x] {
+					img.SetRGBA(x, y, m.sampledColor(x, y, nbIterations))
+					count++
+				}
+			}
+		}
+		progress.tileDone()
+		return nil
+	})
+	return int(refined.Load()), err
+}
+
+// DefaultAdaptiveThreshold is the escape value difference the constructors of Mandelbrot set
+// as AdaptiveThreshold: with the banded coloring, neighbors one iteration apart are not edges.
+const DefaultAdaptiveThreshold = 1.0
+
+// findEdges marks the pixels of b whose escape value differs from one of their 8 neighbors
+// by more than threshold, or which are inside the set while a neighbor is not.
+func findEdges(b EscapeBuffer, coloring Coloring, threshold float64) []bool {
+	values := make([]float64, len(b.Values))
+	for i, e := range b.Values {
+		values[i] = b.Value(e, coloring)
+	}
+
+	edges := make([]bool, len(b.Values))
+	for y := 0; y < b.Height; y++ {
+		for x := 0; x < b.Width; x++ {
+			i := y*b.Width + x
+			inside := b.Inside(b.Values[i])
+			for ny := max(y-1, 0); ny <= min(y+1, b.Height-1) && !edges[i]; ny++ {
+				for nx := max(x-1, 0); nx <= min(x+1, b.Width-1); nx++ {
+					j := ny*b.Width + nx
+					if b.Inside(b.Values[j]) != inside || (!inside && math.Abs(values[j]-values[i]) > threshold) {
+						edges[i] = true
+						break
+					}
+				}
+			}
+		}
+	}
+	return edges
+}
+
+// sampledColor returns the color of the pixel (x, y) averaged over m.Samples*m.Samples samples.
+// The average is computed on linear intensities, averaging sRGB values would darken the edges.
+func (m Mandelbrot) sampledColor(x, y, nbIterations int) color.RGBA {
+	n := m.Samples
+	b := EscapeBuffer{Mandelbrot: m, MaxIterations: nbIterations}
+	palette := m.palette()
+
+	var r, g, bl, a float64
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			dx, dy := 0.5, 0.5
+			if m.Sampling == Jittered {
+				dx, dy = jitter(x, y, i*n+j, 0), jitter(x, y, i*n+j, 1)
+			}
+			sampleX := float64(x) + (float64(j)+dx)/float64(n)
+			sampleY := float64(y) + (float64(i)+dy)/float64(n)
+
+			c := b.color(m.EscapeAtPixel(sampleX, sampleY, nbIterations), palette, m.Coloring)
+			r += srgbToLinear[c.R]
+			g += srgbToLinear[c.G]
+			bl += srgbToLinear[c.B]
+			a += float64(c.A)
+		}
+	}
+	count := float64(n * n)
+	return color.RGBA{
+		R: linearToSRGB(r / count),
+		G: linearToSRGB(g / count),
+		B: linearToSRGB(bl / count),
+		A: uint8(math.Round(a / count)),
+	}
+}
+
+// jitter returns a pseudo random offset in [0, 1) for the given axis of a sample of the pixel (x, y).
+func jitter(x, y, sample, axis int) float64 {
+	// splitmix64 finalizer of the coordinates
+	h := uint64(x)*0x9E3779B97F4A7C15 ^ uint64(y)*0xC2B2AE3D27D4EB4F ^ uint64(2*sample+axis)*0x165667B19E3779F9
+	h ^= h >> 30
+	h *= 0xBF58476D1CE4E5B9
+	h ^= h >> 27
+	h *= 0x94D049BB133111EB
+	h ^= h >> 31
+	return float64(h>>11) / (1 << 53)
+}
+
+// srgbToLinear converts an 8 bits sRGB channel to a linear intensity in [0, 1].
+var srgbToLinear = func() (table [256]float64) {
+	for i := range table {
+		c := float64(i) / 255
+		if c <= 0.04045 {
+			table[i] = c / 12.92
+		} else {
+			table[i] = math.Pow((c+0.055)/1.055, 2.4)
+		}
+	}
+	return table
+}()
+
+// linearToSRGB converts a linear intensity in [0, 1] back to an 8 bits sRGB channel.
+func linearToSRGB(v float64) uint8 {
+	if v <= 0.0031308 {
+		v *= 12.92
+	} else {
+		v = 1.055*math.Pow(v, 1/2.4) - 0.055
+	}
+	return uint8(math.Round(math.Min(math.Max(v, 0), 1) * 255))
+}
@@ -0,0 +1,50 @@
+package mandelbrot
+
+import "testing"
+
+// TestSingleSampleMatchesPlainPixels checks that supersampling takes its samples where
+// plain pixels do, so a pixel sampled once is colored like a pixel which is not supersampled.
+func TestSingleSampleMatchesPlainPixels(t *testing.T) {
+	rotated := NewMandelbrot(100, 80)
+	rotated.Angle = 30
+	smooth := NewJulia(160, 90, complex(-0.8, 0.156))
+	smooth.Coloring = Smooth
+	views := map[string]Mandelbrot{
+		"default": NewMandelbrot(192, 108),
+		"rotated": rotated,
+		"julia":   smooth,
+	}
+	for name, m := range views {
+		m.Samples = 1
+		b := EscapeBuffer{Mandelbrot: m, MaxIterations: 200}
+		for y := 0; y < m.Height; y++ {
+			for x := 0; x < m.Width; x++ {
+				want := b.color(m.pixelEscape(x, y, 200), m.palette(), m.Coloring)
+				if got := m.sampledColor(x, y, 200); got != want {
+					t.Fatalf("%s: pixel (%d, %d) sampled once is %v, want %v", name, x, y, got, want)
+				}
+			}
+		}
+	}
+}
+
+func TestFindEdges(t *testing.T) {
+	b := NewEscapeBuffer(NewMandelbrot(5, 1), 100)
+	b.Values = []Escape{{Iterations: 10}, {Iterations: 10}, {Iterations: 11}, {Iterations: 13}, {Iterations: 100}}
+	tests := []struct {
+		threshold float64
+		want      []bool
+	}{
+		{0, []bool{false, true, true, true, true}},
+		{DefaultAdaptiveThreshold, []bool{false, false, true, true, true}},
+		{5, []bool{false, false, false, true, true}},
+	}
+	for _, test := range tests {
+		edges := findEdges(b, Banded, test.threshold)
+		for i, edge := range edges {
+			if edge != test.want[i] {
+				t.Errorf("threshold %v: pixel %d is an edge: %v, want %v", test.threshold, i, edge, test.want[i])
+			}
+		}
+	}
+}
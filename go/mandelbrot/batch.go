@@ -0,0 +1,72 @@
+package mandelbrot
+
+import "math/cmplx"
+
+// batchLanes is the number of orbits the Batch kernel iterates side by side.
+const batchLanes = 8
+
+// batchable tells whether the escapes of m can be computed by batches of pixels (see batchEscapes):
+// the Batch kernel only iterates Quadratic in float64.
+func (m Mandelbrot) batchable() bool {
+	_, quadratic := m.formula().(Quadratic)
+	return m.Kernel == Batch && quadratic && m.Deep == nil
+}
+
+// batchEscapes computes the escapes of points into escapes like EscapeAt with the Reference kernel,
+// but iterates batchLanes orbits at once: their steps do not depend on each other, so the processor
+// can run them in parallel instead of waiting for the result of the previous multiplication.
+// Each lane holds its own z and c in separate real and imaginary arrays; as soon as its orbit
+// escapes or reaches nbIteration, it is retired and the lane takes the next point.
+func (m Mandelbrot) batchEscapes(points []complex128, escapes []Escape, nbIteration int) {
+	bailout := m.Coloring.Bailout()
+	low := bailout * bailout * (1 - magnitudeMargin)
+	high := bailout * bailout * (1 + magnitudeMargin)
+
+	var zr, zi, cr, ci [batchLanes]float64
+	var iterations [batchLanes]int
+	var pixel [batchLanes]int // index of the point of the lane in points, -1 when the lane is idle
+	next, active := 0, 0
+
+	// load gives the next point which needs iterating to the lane l
+	load := func(l int) {
+		pixel[l] = -1
+		for next < len(points) {
+			i, p := next, points[next]
+			next++
+			if !m.Julia && inMainComponents(real(p), imag(p)) {
+				escapes[i] = Escape{Iterations: nbIteration}
+				continue
+			}
+			z, c := complex128(0), p
+			if m.Julia {
+				z, c = p, m.JuliaC
+			}
+			zr[l], zi[l], cr[l], ci[l] = real(z), imag(z), real(c), imag(c)
+			iterations[l], pixel[l] = 0, i
+			active++
+			return
+		}
+	}
+	for l := range pixel {
+		load(l)
+	}
+
+	for active > 0 {
+		for l := 0; l < batchLanes; l++ {
+			if pixel[l] < 0 {
+				continue
+			}
+			x, y := zr[l], zi[l]
+			r2 := x*x + y*y
+			if iterations[l] == nbIteration || r2 > high || r2 > low && cmplx.Abs(complex(x, y)) > bailout {
+				escapes[pixel[l]] = Escape{Iterations: iterations[l], Modulus: cmplx.Abs(complex(x, y))}
+				active--
+				load(l)
+				continue
+			}
+			// same operations as z*z + c, hence the same rounding
+			zr[l], zi[l] = x*x-y*y+cr[l], x*y+y*x+ci[l]
+			iterations[l]++
+		}
+	}
+}
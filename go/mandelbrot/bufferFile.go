@@ -0,0 +1,260 @@
+package mandelbrot
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"os"
+)
+
+/*
+Escape buffer files (.mbe) store an EscapeBuffer without any loss, so a render can be
+recolored, compared or stitched later. All numbers are little endian.
+
+Header :
+
+	offset  size  field
+	0       4     magic "MBEB"
+	4       2     version, currently 1
+	6       4     width (uint32)
+	10      4     height (uint32)
+	14      8     xMin (float64)
+	22      8     xMax (float64)
+	30      8     yMin (float64)
+	38      8     yMax (float64)
+	46      4     max iterations (uint32)
+	50      8     bailout (float64)
+	58      1     coloring (0 banded, 1 smooth)
+	59      1     julia (0 or 1)
+	60      8     real part of the Julia constant (float64)
+	68      8     imaginary part of the Julia constant (float64)
+	76      2     length n of the formula name (uint16)
+	78      n     formula name, as accepted by FormulaByName
+
+The deep zoom follows, if any (the float64 window is then its approximation) :
+
+	0       4     precision in bits (uint32), 0 without deep zoom
+	4       ...   center x, center y and scale when precision > 0, each one as a
+	              length (uint16) followed by its decimal representation
+
+Then the transformation of the view :
+
+	0       8     angle in degrees (float64)
+	8       8     skew (float64)
+
+Body : width*height pixels row by row, 12 bytes each :
+
+	0       4     iterations (uint32), max iterations for points of the set
+	4       8     modulus |z| at the escape (float64)
+*/
+const (
+	bufferMagic   = "MBEB"
+	bufferVersion = 1
+	pixelSize     = 12
+	// maxBufferPixels bounds the size read from a header, 2^28 escapes taking 4 GiB in memory
+	maxBufferPixels = 1 << 28
+)
+
+// bufferHeader is the fixed size part of the header.
+type bufferHeader struct {
+	Magic         [4]byte
+	Version       uint16
+	Width, Height uint32
+	XMin, XMax    float64
+	YMin, YMax    float64
+	MaxIterations uint32
+	Bailout       float64
+	Coloring      uint8
+	Julia         uint8
+	JuliaC        [2]float64
+	FormulaLength uint16
+}
+
+// WriteEscapeBuffer encodes b to w in the escape buffer file format.
+func WriteEscapeBuffer(w io.Writer, b EscapeBuffer) error {
+	name := b.formula().Name()
+	header := bufferHeader{
+		Version:       bufferVersion,
+		Width:         uint32(b.Width),
+		Height:        uint32(b.Height),
+		XMin:          b.XMin,
+		XMax:          b.XMax,
+		YMin:          b.YMin,
+		YMax:          b.YMax,
+		MaxIterations: uint32(b.MaxIterations),
+		Bailout:       b.Bailout,
+		Coloring:      uint8(b.Coloring),
+		JuliaC:        [2]float64{real(b.JuliaC), imag(b.JuliaC)},
+		FormulaLength: uint16(len(name)),
+	}
+	copy(header.Magic[:], bufferMagic)
+	if b.Julia {
+		header.Julia = 1
+	}
+
+	writer := bufio.NewWriter(w)
+	if err := binary.Write(writer, binary.LittleEndian, header); err != nil {
+		return fmt.Errorf("could not write header: %v", err)
+	}
+	if _, err := writer.WriteString(name); err != nil {
+		return fmt.Errorf("could not write header: %v", err)
+	}
+	if err := writeDeepZoom(writer, b.Deep); err != nil {
+		return fmt.Errorf("could not write header: %v", err)
+	}
+	if err := binary.Write(writer, binary.LittleEndian, [2]float64{b.Angle, b.Skew}); err != nil {
+		return fmt.Errorf("could not write header: %v", err)
+	}
+
+	row := make([]byte, b.Width*pixelSize)
+	for y := 0; y < b.Height; y++ {
+		for x, e := range b.Values[y*b.Width : (y+1)*b.Width] {
+			binary.LittleEndian.PutUint32(row[x*pixelSize:], uint32(e.Iterations))
+			binary.LittleEndian.PutUint64(row[x*pixelSize+4:], math.Float64bits(e.Modulus))
+		}
+		if _, err := writer.Write(row); err != nil {
+			return fmt.Errorf("could not write row %d: %v", y, err)
+		}
+	}
+	return writer.Flush()
+}
+
+// ReadEscapeBuffer decodes an escape buffer written by WriteEscapeBuffer.
+func ReadEscapeBuffer(r io.Reader) (EscapeBuffer, error) {
+	reader := bufio.NewReader(r)
+
+	var header bufferHeader
+	if err := binary.Read(reader, binary.LittleEndian, &header); err != nil {
+		return EscapeBuffer{}, fmt.Errorf("could not read header: %v", err)
+	}
+	if string(header.Magic[:]) != bufferMagic {
+		return EscapeBuffer{}, fmt.Errorf("not an escape buffer file")
+	}
+	if header.Version != bufferVersion {
+		return EscapeBuffer{}, fmt.Errorf("unsupported escape buffer version %d", header.Version)
+	}
+
+	if uint64(header.Width)*uint64(header.Height) > maxBufferPixels {
+		return EscapeBuffer{}, fmt.Errorf("escape buffer of %dx%d pixels is too large", header.Width, header.Height)
+	}
+
+	name := make([]byte, header.FormulaLength)
+	if _, err := io.ReadFull(reader, name); err != nil {
+		return EscapeBuffer{}, fmt.Errorf("could not read header: %v", err)
+	}
+	formula, err := FormulaByName(string(name))
+	if err != nil {
+		return EscapeBuffer{}, err
+	}
+
+	m := Mandelbrot{
+		Width:    int(header.Width),
+		Height:   int(header.Height),
+		XMin:     header.XMin,
+		XMax:     header.XMax,
+		YMin:     header.YMin,
+		YMax:     header.YMax,
+		Formula:  formula,
+		Coloring: Coloring(header.Coloring),
+		Julia:    header.Julia == 1,
+		JuliaC:   complex(header.JuliaC[0], header.JuliaC[1]),
+	}
+	deep, err := readDeepZoom(reader)
+	if err != nil {
+		return EscapeBuffer{}, fmt.Errorf("could not read header: %v", err)
+	}
+	m.Deep = deep
+	var transformation [2]float64
+	if err := binary.Read(reader, binary.LittleEndian, &transformation); err != nil {
+		return EscapeBuffer{}, fmt.Errorf("could not read header: %v", err)
+	}
+	m.Angle, m.Skew = transformation[0], transformation[1]
+	b := NewEscapeBuffer(m, int(header.MaxIterations))
+	b.Bailout = header.Bailout
+
+	row := make([]byte, b.Width*pixelSize)
+	for y := 0; y < b.Height; y++ {
+		if _, err := io.ReadFull(reader, row); err != nil {
+			return EscapeBuffer{}, fmt.Errorf("could not read row %d: %v", y, err)
+		}
+		for x := 0; x < b.Width; x++ {
+			b.Values[y*b.Width+x] = Escape{
+				Iterations: int(binary.LittleEndian.Uint32(row[x*pixelSize:])),
+				Modulus:    math.Float64frombits(binary.LittleEndian.Uint64(row[x*pixelSize+4:])),
+			}
+		}
+	}
+	return b, nil
+}
+
+// writeDeepZoom writes the deep zoom part of the header.
+func writeDeepZoom(w io.Writer, d *DeepZoom) error {
+	if d == nil {
+		return binary.Write(w, binary.LittleEndian, uint32(0))
+	}
+	if err := binary.Write(w, binary.LittleEndian, uint32(d.Precision)); err != nil {
+		return err
+	}
+	for _, f := range []*big.Float{d.CenterX, d.CenterY, d.Scale} {
+		text := f.Text('g', -1)
+		if err := binary.Write(w, binary.LittleEndian, uint16(len(text))); err != nil {
+			return err
+		}
+		if _, err := io.WriteString(w, text); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readDeepZoom reads the deep zoom part of the header, nil when there is none.
+func readDeepZoom(r io.Reader) (*DeepZoom, error) {
+	var precision uint32
+	if err := binary.Read(r, binary.LittleEndian, &precision); err != nil {
+		return nil, err
+	}
+	if precision == 0 {
+		return nil, nil
+	}
+	var texts [3]string
+	for i := range texts {
+		var length uint16
+		if err := binary.Read(r, binary.LittleEndian, &length); err != nil {
+			return nil, err
+		}
+		text := make([]byte, length)
+		if _, err := io.ReadFull(r, text); err != nil {
+			return nil, err
+		}
+		texts[i] = string(text)
+	}
+	return NewDeepZoom(texts[0], texts[1], texts[2], uint(precision))
+}
+
+// SaveEscapeBuffer saves b as an escape buffer file.
+func SaveEscapeBuffer(b EscapeBuffer, filePath string) error {
+	file, err := os.Create(filePath)
+	if err != nil {
+		return fmt.Errorf("could not create file: %v", err)
+	}
+	defer file.Close()
+
+	if err := WriteEscapeBuffer(file, b); err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+// LoadEscapeBuffer reads an escape buffer file.
+func LoadEscapeBuffer(filePath string) (EscapeBuffer, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return EscapeBuffer{}, fmt.Errorf("could not open file: %v", err)
+	}
+	defer file.Close()
+
+	return ReadEscapeBuffer(file)
+}
@@ -0,0 +1,115 @@
+package mandelbrot
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"math"
+	"testing"
+)
+
+// bufferFileViews are the configurations written and read back by the tests.
+func bufferFileViews(t *testing.T) map[string]Mandelbrot {
+	deep := NewMandelbrot(24, 16)
+	d, err := NewDeepZoom("-0.743643887037151", "0.131825904205330", "1e-9", 128)
+	if err != nil {
+		t.Fatal(err)
+	}
+	deep.SetDeepZoom(d)
+	transformed := NewFractal(24, 16, Multibrot{Power: 3})
+	transformed.Angle, transformed.Skew = 30, 0.2
+	smooth := NewJulia(24, 16, complex(-0.8, 0.156))
+	smooth.Coloring = Smooth
+
+	return map[string]Mandelbrot{
+		"default":     NewMandelbrot(24, 16),
+		"deep":        deep,
+		"transformed": transformed,
+		"julia":       smooth,
+	}
+}
+
+func TestEscapeBufferRoundTrip(t *testing.T) {
+	for name, m := range bufferFileViews(t) {
+		want, err := Compute(context.Background(), m, 2, 100)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var file bytes.Buffer
+		if err := WriteEscapeBuffer(&file, want); err != nil {
+			t.Fatal(err)
+		}
+		got, err := ReadEscapeBuffer(&file)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		checkEscapeBuffer(t, name, got, want)
+	}
+}
+
+// checkEscapeBuffer compares everything a buffer file stores.
+func checkEscapeBuffer(t *testing.T, name string, got, want EscapeBuffer) {
+	t.Helper()
+	if got.Width != want.Width || got.Height != want.Height ||
+		got.XMin != want.XMin || got.XMax != want.XMax || got.YMin != want.YMin || got.YMax != want.YMax ||
+		got.MaxIterations != want.MaxIterations || got.Bailout != want.Bailout || got.Coloring != want.Coloring ||
+		got.Julia != want.Julia || got.JuliaC != want.JuliaC || got.Angle != want.Angle || got.Skew != want.Skew {
+		t.Errorf("%s: read %+v, want %+v", name, got.Mandelbrot, want.Mandelbrot)
+	}
+	if got.formula() != want.formula() {
+		t.Errorf("%s: read formula %#v, want %#v", name, got.formula(), want.formula())
+	}
+	if (got.Deep == nil) != (want.Deep == nil) {
+		t.Errorf("%s: read deep zoom %v, want %v", name, got.Deep, want.Deep)
+	} else if want.Deep != nil {
+		if got.Deep.Precision != want.Deep.Precision || got.Deep.CenterX.Cmp(want.Deep.CenterX) != 0 ||
+			got.Deep.CenterY.Cmp(want.Deep.CenterY) != 0 || got.Deep.Scale.Cmp(want.Deep.Scale) != 0 {
+			t.Errorf("%s: read deep zoom %+v, want %+v", name, got.Deep, want.Deep)
+		}
+	}
+	if len(got.Values) != len(want.Values) {
+		t.Fatalf("%s: read %d escapes, want %d", name, len(got.Values), len(want.Values))
+	}
+	for i, e := range want.Values {
+		if got.Values[i] != e {
+			t.Errorf("%s: pixel %d reads as %+v, want %+v", name, i, got.Values[i], e)
+			break
+		}
+	}
+}
+
+func TestReadEscapeBufferErrors(t *testing.T) {
+	b, err := Compute(context.Background(), NewMandelbrot(8, 8), 1, 50)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var file bytes.Buffer
+	if err := WriteEscapeBuffer(&file, b); err != nil {
+		t.Fatal(err)
+	}
+	data := file.Bytes()
+
+	badMagic := append([]byte("MBEX"), data[4:]...)
+	badVersion := append([]byte{}, data...)
+	binary.LittleEndian.PutUint16(badVersion[4:], bufferVersion+1)
+	huge := append([]byte{}, data...)
+	binary.LittleEndian.PutUint32(huge[6:], math.MaxUint32)
+	binary.LittleEndian.PutUint32(huge[10:], math.MaxUint32)
+	tall := append([]byte{}, data...)
+	binary.LittleEndian.PutUint32(tall[10:], 1<<27)
+	tests := map[string][]byte{
+		"empty":            nil,
+		"huge":             huge,
+		"too many pixels":  tall,
+		"bad magic":        badMagic,
+		"bad version":      badVersion,
+		"truncated header": data[:40],
+		"truncated name":   data[:binary.Size(bufferHeader{})+3],
+		"truncated body":   data[:len(data)-pixelSize],
+	}
+	for name, input := range tests {
+		if _, err := ReadEscapeBuffer(bytes.NewReader(input)); err == nil {
+			t.Errorf("%s: ReadEscapeBuffer succeeded, want an error", name)
+		}
+	}
+}
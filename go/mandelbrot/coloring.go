@@ -0,0 +1,61 @@
+package mandelbrot
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Coloring selects how the escape of a point is turned into a color.
+type Coloring int
+
+const (
+	// Banded colors the integer escape count, which shows the level sets as hard bands.
+	Banded Coloring = iota
+	// Smooth colors a fractional escape count renormalized with log(log|z|),
+	// which varies continuously across the bands.
+	Smooth
+)
+
+// Bailout radii: the classic radius 2 keeps the banded look unchanged while the smooth
+// coloring needs a larger one for the renormalization to be accurate.
+const (
+	BandedBailout = 2.0
+	SmoothBailout = 256.0
+)
+
+func (c Coloring) String() string {
+	switch c {
+	case Banded:
+		return "banded"
+	case Smooth:
+		return "smooth"
+	}
+	return fmt.Sprintf("Coloring(%d)", int(c))
+}
+
+// Bailout returns the escape radius used with this coloring.
+func (c Coloring) Bailout() float64 {
+	if c == Smooth {
+		return SmoothBailout
+	}
+	return BandedBailout
+}
+
+// ColoringByName returns the coloring called name (banded or smooth).
+func ColoringByName(name string) (Coloring, error) {
+	switch strings.ToLower(name) {
+	case "", "banded":
+		return Banded, nil
+	case "smooth":
+		return Smooth, nil
+	}
+	return Banded, fmt.Errorf("unknown coloring %q", name)
+}
+
+// SmoothEscape returns the fractional escape count of a sequence which first left the
+// bailout disk at iteration n with |z| = modulus, for a formula of the given degree.
+func SmoothEscape(n int, modulus, degree float64) float64 {
+	mu := float64(n) + 1 - math.Log(math.Log(modulus))/math.Log(degree)
+	return math.Max(mu, 0)
+}
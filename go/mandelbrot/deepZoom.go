@@ -0,0 +1,170 @@
+package mandelbrot
+
+import (
+	"fmt"
+	"math/big"
+)
+
+// DeepZoom describes a view too small for float64 coordinates: its center and scale are
+// big.Float values. By default only the center is iterated with Precision bits of mantissa
+// and the pixels are computed by perturbation around it, Exact iterates every pixel in
+// arbitrary precision instead, which is far slower.
+type DeepZoom struct {
+	// CenterX, CenterY is the point of the complex plane at the center of the image.
+	CenterX, CenterY *big.Float
+	// Scale is the width of the view in the complex plane, its height follows the image ratio.
+	Scale *big.Float
+	// Precision is the mantissa size in bits of the numbers used by the iteration.
+	Precision uint
+	// Exact disables perturbation.
+	Exact bool
+
+	// reference is the orbit of the center used by perturbation, see withReferenceOrbit.
+	reference *referenceOrbit
+}
+
+// NewDeepZoom parses the decimal center and scale of a deep zoom with precision bits.
+func NewDeepZoom(centerX, centerY, scale string, precision uint) (*DeepZoom, error) {
+	if precision == 0 || precision > big.MaxPrec {
+		return nil, fmt.Errorf("invalid precision: %d bits", precision)
+	}
+	parse := func(name, s string) (*big.Float, error) {
+		f, _, err := big.ParseFloat(s, 10, precision, big.ToNearestEven)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %s", name, s)
+		}
+		return f, nil
+	}
+
+	d := &DeepZoom{Precision: precision}
+	var err error
+	if d.CenterX, err = parse("center x", centerX); err != nil {
+		return nil, err
+	}
+	if d.CenterY, err = parse("center y", centerY); err != nil {
+		return nil, err
+	}
+	if d.Scale, err = parse("scale", scale); err != nil {
+		return nil, err
+	}
+	if d.Scale.Sign() <= 0 {
+		return nil, fmt.Errorf("scale must be positive: %s", scale)
+	}
+	return d, nil
+}
+
+// SetDeepZoom switches m to the deep zoom d. The float64 window is kept as the closest
+// approximation of d, for display and for escape buffer headers.
+func (m *Mandelbrot) SetDeepZoom(d *DeepZoom) {
+	m.Deep = d
+	x, _ := d.CenterX.Float64()
+	y, _ := d.CenterY.Float64()
+	width, _ := d.Scale.Float64()
+	height := width * float64(m.Height) / float64(m.Width)
+	m.XMin, m.XMax = x-width/2, x+width/2
+	m.YMin, m.YMax = y-height/2, y+height/2
+}
+
+// EscapeAtPixel iterates the point at the pixel coordinates (x, y) of the image described by m,
+// in arbitrary precision for a deep zoom and in float64 otherwise. The pixel (i, j) spans
+// [i, i+1) x [j, j+1), fractional coordinates are samples inside it.
+func (m Mandelbrot) EscapeAtPixel(x, y float64, nbIteration int) Escape {
+	if m.Deep == nil {
+		return m.EscapeAt(m.Point(x, y), nbIteration)
+	}
+
+	if m.Deep.reference != nil {
+		step := m.Deep.reference.step
+		dx, dy := m.transform((x-float64(m.Width)/2)*step, (y-float64(m.Height)/2)*step)
+		return m.Deep.reference.escapeAt(dx, dy, m.Julia, nbIteration, m.Coloring.Bailout())
+	}
+
+	px, py := m.Deep.point(m, x, y)
+	if m.Julia {
+		cx := new(big.Float).SetPrec(m.Deep.Precision).SetFloat64(real(m.JuliaC))
+		cy := new(big.Float).SetPrec(m.Deep.Precision).SetFloat64(imag(m.JuliaC))
+		return m.Deep.escapeAt(px, py, cx, cy, nbIteration, m.Coloring.Bailout())
+	}
+	zero := new(big.Float)
+	return m.Deep.escapeAt(zero, zero, px, py, nbIteration, m.Coloring.Bailout())
+}
+
+// pixelEscape iterates the center of the pixel (x, y), which stands for the whole pixel
+// when it is not supersampled.
+func (m Mandelbrot) pixelEscape(x, y, nbIteration int) Escape {
+	return m.EscapeAtPixel(float64(x)+0.5, float64(y)+0.5, nbIteration)
+}
+
+// validateDeepZoom checks that the configuration of a deep zoom can be rendered.
+func (m Mandelbrot) validateDeepZoom() error {
+	if m.Deep == nil {
+		return nil
+	}
+	if _, ok := m.formula().(Quadratic); !ok {
+		return fmt.Errorf("deep zoom only supports the mandelbrot formula, not %s", m.formula().Name())
+	}
+	if m.Deep.CenterX == nil || m.Deep.CenterY == nil || m.Deep.Scale == nil || m.Deep.Precision == 0 {
+		return fmt.Errorf("deep zoom needs a center, a scale and a precision")
+	}
+	return nil
+}
+
+// withReferenceOrbit returns m with the reference orbit its deep zoom needs for perturbation,
+// m itself when it does not use perturbation.
+func (m Mandelbrot) withReferenceOrbit(nbIteration int) Mandelbrot {
+	if m.Deep == nil || m.Deep.Exact {
+		return m
+	}
+	deep := *m.Deep
+	deep.reference = computeReferenceOrbit(m, nbIteration)
+	m.Deep = &deep
+	return m
+}
+
+// point returns the coordinates of the pixel (x, y) of the image described by m.
+// The skew and the rotation are applied in pixel units, where float64 is precise enough,
+// the offset is then scaled to the complex plane in arbitrary precision.
+func (d *DeepZoom) point(m Mandelbrot, x, y float64) (*big.Float, *big.Float) {
+	// the center of the view is at the pixel coordinates (width/2, height/2)
+	u, v := m.transform(x-float64(m.Width)/2, y-float64(m.Height)/2)
+	step := new(big.Float).SetPrec(d.Precision).Quo(d.Scale, big.NewFloat(float64(m.Width)))
+	px := new(big.Float).SetPrec(d.Precision).SetFloat64(u)
+	py := new(big.Float).SetPrec(d.Precision).SetFloat64(v)
+	px.Mul(px, step)
+	py.Mul(py, step)
+	return px.Add(px, d.CenterX), py.Add(py, d.CenterY)
+}
+
+// escapeAt iterates z*z + c in arbitrary precision, from 0 with c = (cx, cy)
+// or from (zx, zy) with c = JuliaC for a Julia set.
+func (d *DeepZoom) escapeAt(zx, zy, cx, cy *big.Float, nbIteration int, bailout float64) Escape {
+	prec := d.Precision
+	newFloat := func() *big.Float { return new(big.Float).SetPrec(prec) }
+	x, y := newFloat().Set(zx), newFloat().Set(zy)
+	x2, y2, xy, modulus2 := newFloat(), newFloat(), newFloat(), newFloat()
+	limit := newFloat().SetFloat64(bailout * bailout)
+
+	for n := 0; n < nbIteration; n++ {
+		x2.Mul(x, x)
+		y2.Mul(y, y)
+		modulus2.Add(x2, y2)
+		if modulus2.Cmp(limit) > 0 {
+			return Escape{Iterations: n, Modulus: sqrtFloat(modulus2)}
+		}
+		// z = (x² - y² + cx) + i(2xy + cy)
+		xy.Mul(x, y)
+		y.Add(xy, xy)
+		y.Add(y, cy)
+		x.Sub(x2, y2)
+		x.Add(x, cx)
+	}
+	x2.Mul(x, x)
+	y2.Mul(y, y)
+	return Escape{Iterations: nbIteration, Modulus: sqrtFloat(modulus2.Add(x2, y2))}
+}
+
+// sqrtFloat returns the square root of f as a float64.
+func sqrtFloat(f *big.Float) float64 {
+	root, _ := new(big.Float).Sqrt(f).Float64()
+	return root
+}
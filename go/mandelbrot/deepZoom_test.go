@@ -0,0 +1,122 @@
+package mandelbrot
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/big"
+	"testing"
+)
+
+func TestNewDeepZoomErrors(t *testing.T) {
+	tests := []struct {
+		name                    string
+		centerX, centerY, scale string
+		precision               uint
+	}{
+		{"no precision", "0", "0", "1", 0},
+		{"bad center x", "x", "0", "1", 64},
+		{"bad center y", "0", "0.1.2", "1", 64},
+		{"bad scale", "0", "0", "", 64},
+		{"zero scale", "0", "0", "0", 64},
+		{"negative scale", "0", "0", "-1e-20", 64},
+	}
+	for _, test := range tests {
+		if d, err := NewDeepZoom(test.centerX, test.centerY, test.scale, test.precision); err == nil {
+			t.Errorf("%s: NewDeepZoom = %+v, want an error", test.name, d)
+		}
+	}
+}
+
+func TestDeepZoomRejectsFormulas(t *testing.T) {
+	d, err := NewDeepZoom("0", "0", "1e-20", 128)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := NewFractal(16, 16, BurningShip{})
+	m.SetDeepZoom(d)
+	if _, err := Compute(context.Background(), m, 1, 100); err == nil {
+		t.Error("a burning ship deep zoom rendered, want an error")
+	}
+}
+
+// sameIterations returns the fraction of the pixels of want which got escapes at the same iteration.
+func sameIterations(want, got EscapeBuffer) float64 {
+	same := 0
+	for i, e := range want.Values {
+		if got.Values[i].Iterations == e.Iterations {
+			same++
+		}
+	}
+	return float64(same) / float64(len(want.Values))
+}
+
+// TestExactDeepZoomMatchesFloat64 iterates views float64 resolves well in arbitrary precision.
+func TestExactDeepZoomMatchesFloat64(t *testing.T) {
+	tests := []struct {
+		name string
+		m    Mandelbrot
+		view View
+	}{
+		{"mandelbrot", NewMandelbrot(48, 32), View{CenterX: -0.745, CenterY: 0.113, Scale: 0.01}},
+		{"rotated", NewMandelbrot(48, 32), View{CenterX: -0.745, CenterY: 0.113, Scale: 0.01, Angle: 30, Skew: 0.3}},
+		{"julia", NewJulia(48, 32, complex(-0.8, 0.156)), View{CenterX: 0.1, CenterY: 0.2, Scale: 0.5}},
+	}
+	for _, test := range tests {
+		m := test.m
+		m.SetView(test.view)
+		want, err := Compute(context.Background(), m, 2, 300)
+		if err != nil {
+			t.Fatal(err)
+		}
+		v := test.view
+		d, err := NewDeepZoom(fmt.Sprint(v.CenterX), fmt.Sprint(v.CenterY), fmt.Sprint(v.Scale), 128)
+		if err != nil {
+			t.Fatal(err)
+		}
+		d.Exact = true
+		m.SetDeepZoom(d)
+		got, err := Compute(context.Background(), m, 2, 300)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if same := sameIterations(want, got); same < 0.99 {
+			t.Errorf("%s: %.2f%% of the pixels escape at the same iteration, want 99%%", test.name, 100*same)
+		}
+	}
+}
+
+// TestDeepZoomPoint checks the pixel coordinates of a view too small for float64 offsets.
+func TestDeepZoomPoint(t *testing.T) {
+	d, err := NewDeepZoom("-1.25066", "0.02012", "1e-320", 1200)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name         string
+		angle        float64
+		stepX, stepY float64 // offset between the pixels (0, 0) and (1, 0), in pixels
+	}{
+		{"plain", 0, 1, 0},
+		{"rotated", 90, 0, 1},
+	}
+	for _, test := range tests {
+		m := NewMandelbrot(8, 8)
+		m.Angle = test.angle
+		m.SetDeepZoom(d)
+
+		x, y := d.point(m, 4, 4)
+		if x.Cmp(d.CenterX) != 0 || y.Cmp(d.CenterY) != 0 {
+			t.Errorf("%s: the center of the image is at %v, %v, want %v, %v", test.name, x, y, d.CenterX, d.CenterY)
+		}
+		x0, y0 := d.point(m, 0, 0)
+		x1, y1 := d.point(m, 1, 0)
+		/* the offsets, in units of 1e-320/8 */
+		pixel := new(big.Float).SetPrec(d.Precision).Quo(d.Scale, big.NewFloat(8))
+		stepX, _ := new(big.Float).Quo(x1.Sub(x1, x0), pixel).Float64()
+		stepY, _ := new(big.Float).Quo(y1.Sub(y1, y0), pixel).Float64()
+		if math.Abs(stepX-test.stepX) > 1e-9 || math.Abs(stepY-test.stepY) > 1e-9 {
+			t.Errorf("%s: neighbor pixels are %v, %v pixels apart, want %v, %v", test.name, stepX, stepY, test.stepX, test.stepY)
+		}
+	}
+}
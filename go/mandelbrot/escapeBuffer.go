@@ -0,0 +1,74 @@
+package mandelbrot
+
+import (
+	"image"
+	"image/color"
+)
+
+// Escape is the raw result of iterating a single point.
+type Escape struct {
+	// Iterations is the first iteration at which |z| exceeded the bailout,
+	// or the maximum number of iterations when the sequence stayed bounded.
+	Iterations int
+	// Modulus is |z| at that iteration, which the smooth coloring renormalizes.
+	Modulus float64
+}
+
+// EscapeBuffer holds the escape of every pixel of a render, before any coloring.
+type EscapeBuffer struct {
+	// Mandelbrot is the configuration the buffer was computed with.
+	Mandelbrot
+	MaxIterations int
+	Bailout       float64
+	// Values are stored row by row: the pixel (x, y) is Values[y*Width+x].
+	Values []Escape
+}
+
+// NewEscapeBuffer allocates the buffer of a render of m.
+func NewEscapeBuffer(m Mandelbrot, nbIterations int) EscapeBuffer {
+	return EscapeBuffer{
+		Mandelbrot:    m,
+		MaxIterations: nbIterations,
+		Bailout:       m.Coloring.Bailout(),
+		Values:        make([]Escape, m.Width*m.Height),
+	}
+}
+
+// At returns the escape of the pixel (x, y).
+func (b EscapeBuffer) At(x, y int) Escape {
+	return b.Values[y*b.Width+x]
+}
+
+// Inside reports whether e never escaped, i.e. belongs to the set.
+func (b EscapeBuffer) Inside(e Escape) bool {
+	return e.Iterations >= b.MaxIterations
+}
+
+// Value returns the escape value of e used by palettes: the iteration count with the
+// banded coloring, its renormalized fractional counterpart with the smooth one.
+func (b EscapeBuffer) Value(e Escape, coloring Coloring) float64 {
+	if coloring == Smooth {
+		return SmoothEscape(e.Iterations, e.Modulus, b.formula().Degree())
+	}
+	return float64(e.Iterations)
+}
+
+// Colorize turns the escape buffer into an image using palette and coloring,
+// so a render can be recolored without iterating again.
+func Colorize(b EscapeBuffer, palette Palette, coloring Coloring) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, b.Width, b.Height))
+	for y := 0; y < b.Height; y++ {
+		for x := 0; x < b.Width; x++ {
+			img.SetRGBA(x, y, b.color(b.At(x, y), palette, coloring))
+		}
+	}
+	return img
+}
+
+// color returns the color of e with palette and coloring.
+func (b EscapeBuffer) color(e Escape, palette Palette, coloring Coloring) color.RGBA {
+	if b.Inside(e) {
+		return palette.Interior
+	}
+	return palette.Color(b.Value(e, coloring))
+}
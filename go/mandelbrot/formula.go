@@ -0,0 +1,119 @@
+package mandelbrot
+
+import (
+	"fmt"
+	"math"
+	"math/cmplx"
+	"strconv"
+	"strings"
+)
+
+// Formula is the recurrence z -> f(z, c) iterated for every point of a fractal.
+type Formula interface {
+	// Name identifies the formula, FormulaByName(f.Name()) returns an equivalent formula.
+	Name() string
+	// Iterate computes the term following z for the constant c.
+	Iterate(z, c complex128) complex128
+	// DefaultView returns a 16:9 window framing the whole fractal.
+	DefaultView() (xMin, xMax, yMin, yMax float64)
+	// Degree is the growth rate of |z| once it is large, used to renormalize escape counts.
+	Degree() float64
+}
+
+// Quadratic is the classic Mandelbrot recurrence z*z + c.
+type Quadratic struct{}
+
+func (Quadratic) Name() string { return "mandelbrot" }
+
+func (Quadratic) Iterate(z, c complex128) complex128 { return z*z + c }
+
+func (Quadratic) Degree() float64 { return 2 }
+
+func (Quadratic) DefaultView() (xMin, xMax, yMin, yMax float64) {
+	return XMin, XMax, YMin, YMax
+}
+
+// BurningShip folds z into the first quadrant before squaring it: (|Re z| + i|Im z|)^2 + c.
+// Rows go from YMin at the top to YMax at the bottom, so the ship is drawn upright.
+type BurningShip struct{}
+
+func (BurningShip) Name() string { return "burningship" }
+
+func (BurningShip) Iterate(z, c complex128) complex128 {
+	z = complex(math.Abs(real(z)), math.Abs(imag(z)))
+	return z*z + c
+}
+
+func (BurningShip) Degree() float64 { return 2 }
+
+func (BurningShip) DefaultView() (xMin, xMax, yMin, yMax float64) {
+	return -2.6, 1.9, -2.0, 0.53125
+}
+
+// Tricorn (or Mandelbar) squares the conjugate of z: conj(z)^2 + c.
+type Tricorn struct{}
+
+func (Tricorn) Name() string { return "tricorn" }
+
+func (Tricorn) Iterate(z, c complex128) complex128 {
+	z = cmplx.Conj(z)
+	return z*z + c
+}
+
+func (Tricorn) Degree() float64 { return 2 }
+
+func (Tricorn) DefaultView() (xMin, xMax, yMin, yMax float64) {
+	return -3.7, 3.1, -1.9125, 1.9125
+}
+
+// Multibrot generalizes the Mandelbrot recurrence to z^Power + c.
+// Integer powers which fit an int32 are computed by repeated multiplication, others with cmplx.Pow.
+type Multibrot struct {
+	Power float64
+}
+
+func (f Multibrot) Name() string {
+	return "multibrot:" + strconv.FormatFloat(f.Power, 'g', -1, 64)
+}
+
+func (f Multibrot) Iterate(z, c complex128) complex128 {
+	if f.Power == math.Trunc(f.Power) && f.Power <= math.MaxInt32 {
+		// exponentiation by squaring
+		result := complex128(1)
+		for n := int(f.Power); n > 0; n >>= 1 {
+			if n&1 == 1 {
+				result *= z
+			}
+			z *= z
+		}
+		return result + c
+	}
+	return cmplx.Pow(z, complex(f.Power, 0)) + c
+}
+
+func (f Multibrot) Degree() float64 { return f.Power }
+
+func (f Multibrot) DefaultView() (xMin, xMax, yMin, yMax float64) {
+	return -2.4, 2.4, -1.35, 1.35
+}
+
+// FormulaByName returns the formula called name.
+// Known names are mandelbrot, burningship, tricorn (or mandelbar) and multibrot:<power>.
+func FormulaByName(name string) (Formula, error) {
+	switch strings.ToLower(name) {
+	case "", "mandelbrot":
+		return Quadratic{}, nil
+	case "burningship":
+		return BurningShip{}, nil
+	case "tricorn", "mandelbar":
+		return Tricorn{}, nil
+	}
+	if power, found := strings.CutPrefix(strings.ToLower(name), "multibrot:"); found {
+		p, err := strconv.ParseFloat(power, 64)
+		if err != nil || !(p > 1) || math.IsInf(p, 0) {
+			return nil, fmt.Errorf("invalid multibrot power: %s", power)
+		}
+		return Multibrot{Power: p}, nil
+	}
+	return nil, fmt.Errorf("unknown formula %q", name)
+}
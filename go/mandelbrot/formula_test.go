@@ -0,0 +1,65 @@
+package mandelbrot
+
+import (
+	"math/cmplx"
+	"testing"
+)
+
+func TestFormulaByName(t *testing.T) {
+	tests := []struct {
+		name string
+		want Formula
+	}{
+		{"", Quadratic{}},
+		{"mandelbrot", Quadratic{}},
+		{"Mandelbrot", Quadratic{}},
+		{"burningship", BurningShip{}},
+		{"tricorn", Tricorn{}},
+		{"mandelbar", Tricorn{}},
+		{"multibrot:3", Multibrot{Power: 3}},
+		{"Multibrot:2.5", Multibrot{Power: 2.5}},
+	}
+	for _, test := range tests {
+		got, err := FormulaByName(test.name)
+		if err != nil {
+			t.Errorf("FormulaByName(%q): %v", test.name, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("FormulaByName(%q) = %#v, want %#v", test.name, got, test.want)
+		}
+		/* the name of a formula parses back to the same formula */
+		if again, err := FormulaByName(got.Name()); err != nil || again != got {
+			t.Errorf("FormulaByName(%q) = %#v, %v, want %#v", got.Name(), again, err, got)
+		}
+	}
+}
+
+func TestFormulaByNameErrors(t *testing.T) {
+	for _, name := range []string{"julia", "multibrot:", "multibrot:x", "multibrot:1", "multibrot:-2", "multibrot:inf", "multibrot:NaN"} {
+		if f, err := FormulaByName(name); err == nil {
+			t.Errorf("FormulaByName(%q) = %#v, want an error", name, f)
+		}
+	}
+}
+
+func TestMultibrotIterate(t *testing.T) {
+	tests := []struct {
+		power float64
+		z, c  complex128
+		want  complex128
+	}{
+		{2, 3, 1, 10},
+		{3, 1i, 1, 1 - 1i},
+		{4, 2, 0, 16},
+		{0.5, 4, 1, 3},
+		// too large for an int, 0.5^power is 0
+		{1e300, 0.5, 0.1, 0.1},
+	}
+	for _, test := range tests {
+		got := Multibrot{Power: test.power}.Iterate(test.z, test.c)
+		if cmplx.Abs(got-test.want) > 1e-12 {
+			t.Errorf("multibrot:%v: Iterate(%v, %v) = %v, want %v", test.power, test.z, test.c, got, test.want)
+		}
+	}
+}
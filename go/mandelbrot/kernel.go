@@ -0,0 +1,161 @@
+package mandelbrot
+
+import (
+	"fmt"
+	"math/cmplx"
+	"strings"
+)
+
+// Kernel selects the implementation of the escape time loop of the renders which are not deep zooms.
+// Every float64 kernel classifies the points and counts their iterations like Reference,
+// only the modulus kept for the points inside the set may differ.
+type Kernel int
+
+const (
+	// Reference is the plain loop, comparing |z| with the bailout at every iteration.
+	Reference Kernel = iota
+	// Fast compares |z|² with the squared bailout, skips the iterations of the points
+	// of the main cardioid and of the period-2 bulb, and stops as soon as the orbit cycles.
+	Fast
+	// Batch iterates the pixels of a row of Quadratic by groups of batchLanes (see batchEscapes),
+	// comparing |z|² and skipping the main components like Fast. The samples of the
+	// supersampling and the other formulas are iterated by the Fast kernel.
+	Batch
+	// Float32 and FixedPoint iterate Quadratic like Reference, in float32 and in 64-bit fixed-point
+	// numbers instead of float64 (see float32EscapeTime and fixedEscapeTime). Unlike the other kernels,
+	// they do not classify the points like Reference. Renders of other formulas or of deep zooms
+	// with them fail rather than silently iterating in float64.
+	Float32
+	FixedPoint
+)
+
+func (k Kernel) String() string {
+	switch k {
+	case Reference:
+		return "reference"
+	case Fast:
+		return "fast"
+	case Batch:
+		return "batch"
+	case Float32:
+		return "float32"
+	case FixedPoint:
+		return "fixed"
+	}
+	return fmt.Sprintf("Kernel(%d)", int(k))
+}
+
+// Precision returns the name of the numbers k iterates Quadratic with: float32, fixed or float64,
+// each one being accepted by KernelByName.
+func (k Kernel) Precision() string {
+	switch k {
+	case Float32:
+		return "float32"
+	case FixedPoint:
+		return "fixed"
+	}
+	return "float64"
+}
+
+// KernelByName returns the kernel called name (reference, fast, batch, float32 or fixed),
+// float64 being another name of reference. Reference, the zero Kernel, is the default
+// of the CLI and of the server too, so the empty name stands for it.
+func KernelByName(name string) (Kernel, error) {
+	switch strings.ToLower(name) {
+	case "", "reference", "float64":
+		return Reference, nil
+	case "fast":
+		return Fast, nil
+	case "batch":
+		return Batch, nil
+	case "float32":
+		return Float32, nil
+	case "fixed", "fixedpoint":
+		return FixedPoint, nil
+	}
+	return Reference, fmt.Errorf("unknown kernel %q", name)
+}
+
+// validateKernel checks that the kernel of m can iterate its formula.
+func (m Mandelbrot) validateKernel() error {
+	if m.Kernel != Float32 && m.Kernel != FixedPoint {
+		return nil
+	}
+	if _, ok := m.formula().(Quadratic); !ok {
+		return fmt.Errorf("the %v kernel only supports the mandelbrot formula, not %s", m.Kernel, m.formula().Name())
+	}
+	if m.Deep != nil {
+		return fmt.Errorf("the %v kernel does not support deep zooms", m.Kernel)
+	}
+	return nil
+}
+
+// escapeTime iterates f from z with the kernel k, see escapeTime.
+func (k Kernel) escapeTime(f Formula, z, c complex128, nbIteration int, bailout float64) (int, complex128) {
+	_, quadratic := f.(Quadratic)
+	switch {
+	case k == Fast || k == Batch:
+		return fastEscapeTime(f, z, c, nbIteration, bailout)
+	case k == Float32 && quadratic:
+		return float32EscapeTime(z, c, nbIteration, bailout)
+	case k == FixedPoint && quadratic:
+		return fixedEscapeTime(z, c, nbIteration, bailout)
+	}
+	return escapeTime(f, z, c, nbIteration, bailout)
+}
+
+// magnitudeMargin is the relative distance to the squared bailout under which fastEscapeTime
+// checks |z| like escapeTime, so that rounding never makes them disagree.
+const magnitudeMargin = 1e-9
+
+// fastEscapeTime returns the same iteration count as escapeTime, faster:
+//   - |z|² is compared with bailout², only the values rounding could misjudge are checked with cmplx.Abs
+//   - the Mandelbrot set of Quadratic contains its main cardioid and its period-2 bulb,
+//     their points are known to stay bounded without iterating them
+//   - when z comes back exactly to a previous value, the orbit cycles forever and cannot escape
+//     (Brent's method: z is compared with a value saved at every power of 2 iterations)
+func fastEscapeTime(f Formula, z, c complex128, nbIteration int, bailout float64) (int, complex128) {
+	_, quadratic := f.(Quadratic)
+	if quadratic && z == 0 && inMainComponents(real(c), imag(c)) {
+		return nbIteration, z
+	}
+
+	low := bailout * bailout * (1 - magnitudeMargin)
+	high := bailout * bailout * (1 + magnitudeMargin)
+	saved, period, limit := z, 0, 8
+	x, y := real(z), imag(z)
+	cx, cy := real(c), imag(c)
+	for n := 0; n < nbIteration; n++ {
+		r2 := x*x + y*y
+		if r2 > high || r2 > low && cmplx.Abs(complex(x, y)) > bailout {
+			return n, complex(x, y)
+		}
+		if quadratic {
+			// same operations as z*z + c, hence the same rounding
+			x, y = x*x-y*y+cx, x*y+y*x+cy
+		} else {
+			z = f.Iterate(complex(x, y), c)
+			x, y = real(z), imag(z)
+		}
+
+		z = complex(x, y)
+		if z == saved {
+			return nbIteration, z
+		}
+		period++
+		if period == limit {
+			saved, period, limit = z, 0, limit*2
+		}
+	}
+	return nbIteration, complex(x, y)
+}
+
+// inMainComponents tells whether c = x+iy is inside the main cardioid or the period-2 bulb
+// of the Mandelbrot set.
+func inMainComponents(x, y float64) bool {
+	q := (x-0.25)*(x-0.25) + y*y
+	if q*(q+(x-0.25)) <= 0.25*y*y {
+		return true
+	}
+	return (x+1)*(x+1)+y*y <= 0.0625
+}
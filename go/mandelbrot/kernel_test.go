@@ -0,0 +1,241 @@
+package mandelbrot
+
+import (
+	"context"
+	"math"
+	"testing"
+)
+
+// kernelViews are the configurations on which the kernels must agree with Reference.
+func kernelViews() map[string]Mandelbrot {
+	seahorse := NewMandelbrot(160, 90)
+	seahorse.SetView(View{CenterX: -0.745, CenterY: 0.113, Scale: 0.01})
+	cusp := NewMandelbrot(120, 120)
+	cusp.SetView(View{CenterX: 0.25, CenterY: 0, Scale: 1e-6})
+	bulbs := NewMandelbrot(120, 120)
+	bulbs.SetView(View{CenterX: -0.75, CenterY: 0, Scale: 1e-4})
+	smooth := NewMandelbrot(160, 90)
+	smooth.Coloring = Smooth
+	rotated := NewMandelbrot(100, 80)
+	rotated.Angle = 30
+	multibrot, _ := FormulaByName("multibrot:3")
+
+	return map[string]Mandelbrot{
+		"default":     NewMandelbrot(192, 108),
+		"seahorse":    seahorse,
+		"cusp":        cusp,
+		"bulbs":       bulbs,
+		"smooth":      smooth,
+		"rotated":     rotated,
+		"julia":       NewJulia(160, 90, complex(-0.8, 0.156)),
+		"julia-disk":  NewJulia(100, 60, complex(-0.1, 0.1)),
+		"burningship": NewFractal(160, 90, BurningShip{}),
+		"tricorn":     NewFractal(160, 90, Tricorn{}),
+		"multibrot":   NewFractal(160, 90, multibrot),
+	}
+}
+
+// checkKernel compares the escapes of every view computed with kernel to the ones of Reference.
+func checkKernel(t *testing.T, kernel Kernel, nbIterations int) {
+	for name, m := range kernelViews() {
+		want, err := Compute(context.Background(), m, 1, nbIterations)
+		if err != nil {
+			t.Fatal(err)
+		}
+		m.Kernel = kernel
+		got, err := Compute(context.Background(), m, 1, nbIterations)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, e := range want.Values {
+			g := got.Values[i]
+			// the modulus of the points inside the set means nothing
+			if g.Iterations != e.Iterations || !want.Inside(e) && g.Modulus != e.Modulus {
+				t.Errorf("%s, %v kernel: pixel (%d, %d) escapes as %+v, want %+v", name, kernel, i%m.Width, i/m.Width, g, e)
+				break
+			}
+		}
+	}
+}
+
+func TestFastKernelMatchesReference(t *testing.T) {
+	for _, n := range []int{1, 50, 1000, 5000} {
+		checkKernel(t, Fast, n)
+	}
+}
+
+func TestBatchKernelMatchesReference(t *testing.T) {
+	for _, n := range []int{0, 1, 50, 1000, 5000} {
+		checkKernel(t, Batch, n)
+	}
+}
+
+func TestBatchEscapesOddLengths(t *testing.T) {
+	m := NewMandelbrot(1, 1)
+	m.Kernel = Batch
+	for length := 0; length < 3*batchLanes; length++ {
+		points := make([]complex128, length)
+		for i := range points {
+			points[i] = complex(-2+3*float64(i)/float64(3*batchLanes), 0.4)
+		}
+		escapes := make([]Escape, length)
+		m.batchEscapes(points, escapes, 500)
+		for i, p := range points {
+			if want := NewMandelbrot(1, 1).EscapeAt(p, 500); escapes[i].Iterations != want.Iterations {
+				t.Fatalf("%d points: point %v escapes at %d, want %d", length, p, escapes[i].Iterations, want.Iterations)
+			}
+		}
+	}
+}
+
+func TestInMainComponents(t *testing.T) {
+	inside := []complex128{0, -0.5, 0.2, complex(-0.1, 0.6), -1, complex(-1.2, 0.1), complex(0.25, 0)}
+	outside := []complex128{0.26, complex(-0.75, 0.1), -1.26, complex(0.3, 0.5), complex(-0.1, 0.7), 2}
+	for _, c := range inside {
+		if !inMainComponents(real(c), imag(c)) {
+			t.Errorf("%v should be in the main components", c)
+		}
+	}
+	for _, c := range outside {
+		if inMainComponents(real(c), imag(c)) {
+			t.Errorf("%v should not be in the main components", c)
+		}
+	}
+}
+
+// BenchmarkKernel computes the standard 3840x2160 view of the CLI with each kernel.
+func BenchmarkKernel(b *testing.B) {
+	for _, kernel := range []Kernel{Reference, Fast, Batch, Float32, FixedPoint} {
+		m := NewMandelbrot(3840, 2160)
+		m.Kernel = kernel
+		b.Run(kernel.String(), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := Compute(context.Background(), m, 0, 1000); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// agreement returns the fraction of the pixels of m which kernel classifies like Reference.
+func agreement(t *testing.T, m Mandelbrot, kernel Kernel, nbIterations int) float64 {
+	want, err := Compute(context.Background(), m, 1, nbIterations)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.Kernel = kernel
+	got, err := Compute(context.Background(), m, 1, nbIterations)
+	if err != nil {
+		t.Fatal(err)
+	}
+	same := 0
+	for i, e := range want.Values {
+		if want.Inside(e) == got.Inside(got.Values[i]) {
+			same++
+		}
+	}
+	return float64(same) / float64(len(want.Values))
+}
+
+func TestPrecisionKernels(t *testing.T) {
+	deep := NewMandelbrot(160, 90)
+	deep.SetView(View{CenterX: -0.743643887037151, CenterY: 0.13182590420533, Scale: 1e-6})
+	smooth := NewMandelbrot(160, 90)
+	smooth.Coloring = Smooth
+
+	tests := []struct {
+		name   string
+		m      Mandelbrot
+		kernel Kernel
+		min    float64 // minimum agreement with Reference
+	}{
+		{"float32", NewMandelbrot(192, 108), Float32, 0.999},
+		{"fixed", NewMandelbrot(192, 108), FixedPoint, 0.9999},
+		{"fixed smooth", smooth, FixedPoint, 0.999},
+		{"fixed deep", deep, FixedPoint, 0.99},
+	}
+	for _, test := range tests {
+		a := agreement(t, test.m, test.kernel, 1000)
+		t.Logf("%s: %.3f%% of the pixels classified like the reference", test.name, 100*a)
+		if a < test.min {
+			t.Errorf("%s: %.3f%% of the pixels classified like the reference, want at least %.2f%%", test.name, 100*a, 100*test.min)
+		}
+	}
+
+	/* float32 has 24 bits of mantissa: neighbor pixels 1e-8 apart are the same point,
+	so it must lose at least a percent of the pixels that fixed point keeps */
+	fixed := agreement(t, deep, FixedPoint, 1000)
+	single := agreement(t, deep, Float32, 1000)
+	t.Logf("float32 deep: %.3f%% of the pixels classified like the reference", 100*single)
+	if single > fixed-0.01 {
+		t.Errorf("float32 deep: %.3f%% of the pixels classified like the reference, want less than %.3f%%", 100*single, 100*(fixed-0.01))
+	}
+}
+
+func TestFixedArithmetic(t *testing.T) {
+	for _, bailout := range []float64{BandedBailout, SmoothBailout} {
+		frac := fixedFormat(bailout)
+		values := []float64{0, 1, -1, 0.5, -1.75, bailout, -bailout, bailout*bailout + bailout}
+		for i, a := range values {
+			if got := toFixed(a, frac).float(frac); got != a {
+				t.Errorf("bailout %v: %v converts back to %v", bailout, a, got)
+			}
+			// the products of the iterations never exceed bailout²
+			for _, b := range values[:min(i+1, 6)] {
+				if math.Abs(a*b) > bailout*bailout {
+					continue
+				}
+				got := toFixed(a, frac).mul(toFixed(b, frac), frac).float(frac)
+				if math.Abs(got-a*b) > math.Ldexp(1, -int(frac)) {
+					t.Errorf("bailout %v: %v * %v = %v, want %v", bailout, a, b, got, a*b)
+				}
+			}
+		}
+	}
+}
+
+func TestKernelPrecision(t *testing.T) {
+	tests := []struct {
+		kernel    Kernel
+		precision string
+	}{
+		{Reference, "float64"},
+		{Fast, "float64"},
+		{Batch, "float64"},
+		{Float32, "float32"},
+		{FixedPoint, "fixed"},
+	}
+	for _, test := range tests {
+		if got := test.kernel.Precision(); got != test.precision {
+			t.Errorf("%v kernel: precision %q, want %q", test.kernel, got, test.precision)
+		}
+		if k, err := KernelByName(test.kernel.String()); err != nil || k != test.kernel {
+			t.Errorf("KernelByName(%q) = %v, %v, want %v", test.kernel.String(), k, err, test.kernel)
+		}
+	}
+}
+
+func TestPrecisionKernelsRejectOtherFormulas(t *testing.T) {
+	deep := NewMandelbrot(16, 16)
+	d, err := NewDeepZoom("-0.75", "0.1", "1e-20", 128)
+	if err != nil {
+		t.Fatal(err)
+	}
+	deep.SetDeepZoom(d)
+	for _, kernel := range []Kernel{Float32, FixedPoint} {
+		for name, m := range map[string]Mandelbrot{
+			"burningship": NewFractal(16, 16, BurningShip{}),
+			"multibrot":   NewFractal(16, 16, Multibrot{Power: 3}),
+			"deep":        deep,
+		} {
+			m.Kernel = kernel
+			if _, err := Compute(context.Background(), m, 1, 100); err == nil {
+				t.Errorf("%s: computed with the %v kernel, want an error", name, kernel)
+			}
+			if _, _, err := Render(context.Background(), m, 1, 100); err == nil {
+				t.Errorf("%s: rendered with the %v kernel, want an error", name, kernel)
+			}
+		}
+	}
+}
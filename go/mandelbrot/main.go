@@ -1,28 +1,88 @@
 package mandelbrot
 
-// Constants for the range of the Mandelbrot set
-const (
-	/*
-		(xMax - xMin) / (yMax - yMin) = 16:9
-	*/
-	XMin, XMax = -1.5 * 2.0, 1.5 * 1.0
-	YMin, YMax = -1.5 * 0.84375, 1.5 * 0.84375
+import (
+	"image/color"
+	"math/cmplx"
 )
 
-type Mandelbrot struct {
-	Width, Height int
-	XMin, XMax    float64
-	YMin, YMax    float64
-}
-
-// NewMandelbrot initializes a new Mandelbrot set configuration with specified dimensions.
-func NewMandelbrot(width, height int) Mandelbrot {
-	return Mandelbrot{
-		Width:  width,
-		Height: height,
-		XMin:   XMin,
-		XMax:   XMax,
-		YMin:   YMin,
-		YMax:   YMax,
+// ColorConvergence determines the color of a point based on the Mandelbrot set calculation.
+// It returns a color based on the number of iterations it takes for the sequence to escape.
+func ColorConvergence(c complex128, nbIteration int) (color.RGBA, error) {
+	return ColorJulia(0, c, nbIteration)
+}
+
+// ColorJulia determines the color of a point of the Julia set of c.
+// The sequence starts at z instead of 0, otherwise it is colored like ColorConvergence.
+func ColorJulia(z, c complex128, nbIteration int) (color.RGBA, error) {
+	return ColorFormula(Quadratic{}, z, c, nbIteration)
+}
+
+// ColorFormula determines the color of a point when iterating f from z with the constant c.
+func ColorFormula(f Formula, z, c complex128, nbIteration int) (color.RGBA, error) {
+	n, _ := escapeTime(f, z, c, nbIteration, BandedBailout)
+	if n == nbIteration {
+		return color.RGBA{R: 0, G: 0, B: 0, A: 255}, nil // Points in the set are black.
+	}
+	return escapeColor(n), nil
+}
+
+// escapeTime iterates f from z and returns the first iteration at which |z| exceeded bailout
+// along with the value of z at that point, or nbIteration if the sequence stayed bounded.
+func escapeTime(f Formula, z, c complex128, nbIteration int, bailout float64) (int, complex128) {
+	if _, ok := f.(Quadratic); ok {
+		// keeps the classic recurrence free of the interface call
+		for n := 0; n < nbIteration; n++ {
+			if cmplx.Abs(z) > bailout {
+				return n, z
+			}
+			z = z*z + c
+		}
+		return nbIteration, z
+	}
+	for n := 0; n < nbIteration; n++ {
+		if cmplx.Abs(z) > bailout {
+			return n, z
+		}
+		z = f.Iterate(z, c)
+	}
+	return nbIteration, z
+}
+
+// escapeColor maps the escape iteration n to a color gradient.
+func escapeColor(n int) color.RGBA {
+	mod := func(n, m int) int { return (n%m + m) % m } // stays positive when a palette offset makes n negative
+	return color.RGBA{R: uint8(255 - mod(n, 32)*8), G: uint8(mod(n, 64) * 4), B: uint8(255 - mod(n, 16)*16), A: 255}
+}
+
+// EscapeAt iterates the point p of the complex plane according to the configuration m:
+// p is the constant c for the Mandelbrot set and the starting z for a Julia set.
+func (m Mandelbrot) EscapeAt(p complex128, nbIteration int) Escape {
+	z, c := complex128(0), p
+	if m.Julia {
+		z, c = p, m.JuliaC
+	}
+	n, z := m.Kernel.escapeTime(m.formula(), z, c, nbIteration, m.Coloring.Bailout())
+	return Escape{Iterations: n, Modulus: cmplx.Abs(z)}
+}
+
+// ColorAt colors the point p of the complex plane according to the configuration m.
+func (m Mandelbrot) ColorAt(p complex128, nbIteration int) (color.RGBA, error) {
+	b := EscapeBuffer{Mandelbrot: m, MaxIterations: nbIteration}
+	return b.color(m.EscapeAt(p, nbIteration), m.palette(), m.Coloring), nil
+}
+
+// formula returns the formula iterated by m, Quadratic when none is set.
+func (m Mandelbrot) formula() Formula {
+	if m.Formula == nil {
+		return Quadratic{}
+	}
+	return m.Formula
+}
+
+// palette returns the palette of m, the classic one when none is set.
+func (m Mandelbrot) palette() Palette {
+	if m.Palette.Name == "" && len(m.Palette.Stops) == 0 {
+		return ClassicPalette()
 	}
+	return m.Palette
 }
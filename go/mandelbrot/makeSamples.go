@@ -1,20 +1,107 @@
 package mandelbrot
 
-import (
-	"image/color"
-	"math/cmplx"
+// Constants for the range of the Mandelbrot set
+const (
+	/*
+		(xMax - xMin) / (yMax - yMin) = 16:9
+	*/
+	XMin, XMax = -1.5 * 2.0, 1.5 * 1.0
+	YMin, YMax = -1.5 * 0.84375, 1.5 * 0.84375
 )
 
-// ColorConvergence determines the color of a point based on the Mandelbrot set calculation.
-// It returns a color based on the number of iterations it takes for the sequence to escape.
-func ColorConvergence(c complex128, nbIteration int) (color.RGBA, error) {
-	var z complex128
-	for n := 0; n < nbIteration; n++ {
-		if cmplx.Abs(z) > 2 {
-			// Map the escape iteration to a color gradient.
-			return color.RGBA{R: uint8(255 - n%32*8), G: uint8(n % 64 * 4), B: uint8(255 - n%16*16), A: 255}, nil
-		}
-		z = z*z + c
+// Constants for the range of a Julia set, centered on the origin
+const (
+	/*
+		(xMax - xMin) / (yMax - yMin) = 16:9
+	*/
+	JuliaXMin, JuliaXMax = -1.6 * 1.5, 1.6 * 1.5
+	JuliaYMin, JuliaYMax = -1.6 * 0.84375, 1.6 * 0.84375
+)
+
+type Mandelbrot struct {
+	Width, Height int
+	XMin, XMax    float64
+	YMin, YMax    float64
+	// Angle rotates the image counterclockwise around the center of the window, in degrees.
+	Angle float64
+	// Skew shears the image before the rotation: a point at a vertical distance v
+	// from the center is moved horizontally by Skew*v.
+	Skew float64
+
+	// Formula is the recurrence iterated for each point, nil means Quadratic.
+	Formula Formula
+	// Kernel is the implementation of the escape time loop, when not rendering a deep zoom.
+	Kernel Kernel
+	// Strategy selects which pixels are iterated, the others being deduced from their neighbors.
+	Strategy Strategy
+
+	// Coloring selects banded (integer) or smooth (fractional) escape counts.
+	Coloring Coloring
+	// Palette maps escape values to colors, the zero Palette stands for ClassicPalette.
+	Palette Palette
+
+	// Samples supersamples every pixel with a Samples x Samples grid when above 1,
+	// placed according to Sampling.
+	Samples  int
+	Sampling Sampling
+	// Adaptive only supersamples the pixels whose escape value differs from a neighbor's
+	// by more than AdaptiveThreshold, 0 supersamples every pixel with a different value.
+	// The constructors set it to DefaultAdaptiveThreshold.
+	Adaptive          bool
+	AdaptiveThreshold float64
+
+	// Deep, when set, replaces the float64 window by an arbitrary precision one (see SetDeepZoom).
+	Deep *DeepZoom
+
+	// Julia renders the Julia set of JuliaC instead of the Mandelbrot set:
+	// c stays fixed for the whole image and each pixel is the starting z.
+	Julia  bool
+	JuliaC complex128
+
+	// OnProgress, when set, is called each time a worker finishes a tile of the render.
+	// The calls are never concurrent, but come from the workers: it should return quickly.
+	OnProgress func(Progress)
+}
+
+// NewMandelbrot initializes a new Mandelbrot set configuration with specified dimensions.
+func NewMandelbrot(width, height int) Mandelbrot {
+	return Mandelbrot{
+		Width:             width,
+		Height:            height,
+		XMin:              XMin,
+		XMax:              XMax,
+		YMin:              YMin,
+		YMax:              YMax,
+		AdaptiveThreshold: DefaultAdaptiveThreshold,
+	}
+}
+
+// NewFractal initializes the configuration of the fractal of formula f, framed by its default view.
+func NewFractal(width, height int, f Formula) Mandelbrot {
+	xMin, xMax, yMin, yMax := f.DefaultView()
+	return Mandelbrot{
+		Width:             width,
+		Height:            height,
+		XMin:              xMin,
+		XMax:              xMax,
+		YMin:              yMin,
+		YMax:              yMax,
+		Formula:           f,
+		AdaptiveThreshold: DefaultAdaptiveThreshold,
+	}
+}
+
+// NewJulia initializes the configuration of the Julia set of c with specified dimensions.
+func NewJulia(width, height int, c complex128) Mandelbrot {
+	return Mandelbrot{
+		Width:             width,
+		Height:            height,
+		XMin:              JuliaXMin,
+		XMax:              JuliaXMax,
+		YMin:              JuliaYMin,
+		YMax:              JuliaYMax,
+		Julia:             true,
+		JuliaC:            c,
+		AdaptiveThreshold: DefaultAdaptiveThreshold,
 	}
-	return color.RGBA{R: 0, G: 0, B: 0, A: 255}, nil // Points in the Mandelbrot set are black.
 }
@@ -0,0 +1,213 @@
+package mandelbrot
+
+import (
+	"encoding/json"
+	"fmt"
+	"image/color"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ColorStop is a control point of a gradient: Color is reached at position Pos, in [0, 1].
+type ColorStop struct {
+	Pos   float64
+	Color color.RGBA
+}
+
+// Palette maps escape values to colors.
+//
+// An escape value v (an iteration count, fractional with the smooth coloring) is first
+// shifted and stretched into x = (v + Offset) / Scale. A gradient palette then reads its
+// color at position x, repeating every unit when Cyclic and clamping to [0, 1] otherwise.
+// A palette without stops is the classic palette: x is colored as an iteration count by
+// the original RGB formula, interpolated between consecutive counts.
+type Palette struct {
+	Name  string
+	Stops []ColorStop
+	// Cyclic repeats the gradient instead of clamping it to its end stops.
+	Cyclic bool
+	// Offset is added to the escape value and Scale divides it, a zero Scale stands for 1.
+	Offset, Scale float64
+	// Interior is the color of points that never escape.
+	Interior color.RGBA
+}
+
+var black = color.RGBA{R: 0, G: 0, B: 0, A: 255}
+
+// builtinPalettes are the palettes available by name, see PaletteByName.
+var builtinPalettes = map[string]Palette{
+	"classic": {Name: "classic", Interior: black},
+	"grayscale": {Name: "grayscale", Cyclic: true, Scale: 64, Interior: black, Stops: []ColorStop{
+		{0, color.RGBA{0, 0, 0, 255}},
+		{0.5, color.RGBA{255, 255, 255, 255}},
+		{1, color.RGBA{0, 0, 0, 255}},
+	}},
+	"fire": {Name: "fire", Cyclic: true, Scale: 96, Interior: black, Stops: []ColorStop{
+		{0, color.RGBA{20, 0, 0, 255}},
+		{0.3, color.RGBA{200, 30, 0, 255}},
+		{0.55, color.RGBA{255, 170, 0, 255}},
+		{0.75, color.RGBA{255, 255, 200, 255}},
+		{1, color.RGBA{20, 0, 0, 255}},
+	}},
+	"ultra": {Name: "ultra", Cyclic: true, Scale: 48, Interior: black, Stops: []ColorStop{
+		{0, color.RGBA{0, 7, 100, 255}},
+		{0.16, color.RGBA{32, 107, 203, 255}},
+		{0.42, color.RGBA{237, 255, 255, 255}},
+		{0.6425, color.RGBA{255, 170, 0, 255}},
+		{0.8575, color.RGBA{0, 2, 0, 255}},
+		{1, color.RGBA{0, 7, 100, 255}},
+	}},
+}
+
+// ClassicPalette returns the original palette of the renderer.
+func ClassicPalette() Palette {
+	return builtinPalettes["classic"]
+}
+
+// PaletteByName returns the built-in palette called name: classic, grayscale, fire or ultra.
+func PaletteByName(name string) (Palette, error) {
+	if name == "" {
+		return ClassicPalette(), nil
+	}
+	p, ok := builtinPalettes[strings.ToLower(name)]
+	if !ok {
+		return Palette{}, fmt.Errorf("unknown palette %q", name)
+	}
+	return p, nil
+}
+
+// Color returns the color of the escape value v.
+func (p Palette) Color(v float64) color.RGBA {
+	scale := p.Scale
+	if scale == 0 {
+		scale = 1
+	}
+	x := (v + p.Offset) / scale
+
+	if len(p.Stops) == 0 {
+		n := math.Floor(x)
+		return lerpColor(escapeColor(int(n)), escapeColor(int(n)+1), x-n)
+	}
+
+	if p.Cyclic {
+		x -= math.Floor(x)
+	} else {
+		x = math.Min(math.Max(x, 0), 1)
+	}
+	// index of the first stop after x
+	i := sort.Search(len(p.Stops), func(i int) bool { return p.Stops[i].Pos > x })
+	if i == 0 {
+		return p.Stops[0].Color
+	}
+	if i == len(p.Stops) {
+		return p.Stops[i-1].Color
+	}
+	from, to := p.Stops[i-1], p.Stops[i]
+	return lerpColor(from.Color, to.Color, (x-from.Pos)/(to.Pos-from.Pos))
+}
+
+// lerpColor linearly interpolates from a (t = 0) to b (t = 1).
+func lerpColor(a, b color.RGBA, t float64) color.RGBA {
+	if t == 0 {
+		// banded escape values fall on a color
+		return a
+	}
+	lerp := func(a, b uint8) uint8 {
+		return uint8(math.Round(float64(a) + (float64(b)-float64(a))*t))
+	}
+	return color.RGBA{R: lerp(a.R, b.R), G: lerp(a.G, b.G), B: lerp(a.B, b.B), A: lerp(a.A, b.A)}
+}
+
+/*
+paletteFile is the JSON representation of a palette, for example :
+
+	{
+		"name": "sunset",
+		"cyclic": true,
+		"offset": 0,
+		"scale": 48,
+		"interior": "#000000",
+		"stops": [
+			{"pos": 0, "color": "#20104a"},
+			{"pos": 0.5, "color": "#ff8000"},
+			{"pos": 1, "color": "#20104a"}
+		]
+	}
+*/
+type paletteFile struct {
+	Name     string  `json:"name"`
+	Cyclic   bool    `json:"cyclic"`
+	Offset   float64 `json:"offset"`
+	Scale    float64 `json:"scale"`
+	Interior string  `json:"interior"`
+	Stops    []struct {
+		Pos   float64 `json:"pos"`
+		Color string  `json:"color"`
+	} `json:"stops"`
+}
+
+// ReadPalette decodes a gradient palette in the JSON format of paletteFile.
+func ReadPalette(r io.Reader) (Palette, error) {
+	var file paletteFile
+	if err := json.NewDecoder(r).Decode(&file); err != nil {
+		return Palette{}, fmt.Errorf("could not decode palette: %v", err)
+	}
+	if len(file.Stops) < 2 {
+		return Palette{}, fmt.Errorf("a palette needs at least 2 stops, got %d", len(file.Stops))
+	}
+
+	p := Palette{Name: file.Name, Cyclic: file.Cyclic, Offset: file.Offset, Scale: file.Scale, Interior: black}
+	if file.Interior != "" {
+		interior, err := parseHexColor(file.Interior)
+		if err != nil {
+			return Palette{}, err
+		}
+		p.Interior = interior
+	}
+	for _, stop := range file.Stops {
+		if stop.Pos < 0 || stop.Pos > 1 {
+			return Palette{}, fmt.Errorf("stop position %v out of [0, 1]", stop.Pos)
+		}
+		c, err := parseHexColor(stop.Color)
+		if err != nil {
+			return Palette{}, err
+		}
+		p.Stops = append(p.Stops, ColorStop{Pos: stop.Pos, Color: c})
+	}
+	sort.SliceStable(p.Stops, func(i, j int) bool { return p.Stops[i].Pos < p.Stops[j].Pos })
+	return p, nil
+}
+
+// LoadPalette reads a palette file, named after the file when it has no name.
+func LoadPalette(filePath string) (Palette, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return Palette{}, fmt.Errorf("could not open palette: %v", err)
+	}
+	defer file.Close()
+
+	p, err := ReadPalette(file)
+	if err != nil {
+		return Palette{}, err
+	}
+	if p.Name == "" {
+		p.Name = strings.TrimSuffix(filepath.Base(filePath), filepath.Ext(filePath))
+	}
+	return p, nil
+}
+
+// parseHexColor parses an opaque color written #rrggbb.
+func parseHexColor(s string) (color.RGBA, error) {
+	c := color.RGBA{A: 255}
+	if len(s) != 7 || s[0] != '#' {
+		return c, fmt.Errorf("invalid color %q, expected #rrggbb", s)
+	}
+	if _, err := fmt.Sscanf(s[1:], "%02x%02x%02x", &c.R, &c.G, &c.B); err != nil {
+		return c, fmt.Errorf("invalid color %q, expected #rrggbb", s)
+	}
+	return c, nil
+}
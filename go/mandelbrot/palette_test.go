@@ -0,0 +1,107 @@
+package mandelbrot
+
+import (
+	"image/color"
+	"strings"
+	"testing"
+)
+
+func TestReadPalette(t *testing.T) {
+	p, err := ReadPalette(strings.NewReader(`{
+		"name": "sunset",
+		"cyclic": true,
+		"offset": 2,
+		"scale": 48,
+		"interior": "#102030",
+		"stops": [
+			{"pos": 1, "color": "#000080"},
+			{"pos": 0, "color": "#ff0000"},
+			{"pos": 0.5, "color": "#00ff00"}
+		]
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Palette{
+		Name: "sunset", Cyclic: true, Offset: 2, Scale: 48,
+		Interior: color.RGBA{0x10, 0x20, 0x30, 255},
+		Stops: []ColorStop{
+			{0, color.RGBA{255, 0, 0, 255}},
+			{0.5, color.RGBA{0, 255, 0, 255}},
+			{1, color.RGBA{0, 0, 128, 255}},
+		},
+	}
+	if p.Name != want.Name || p.Cyclic != want.Cyclic || p.Offset != want.Offset || p.Scale != want.Scale || p.Interior != want.Interior {
+		t.Errorf("ReadPalette = %+v, want %+v", p, want)
+	}
+	if len(p.Stops) != len(want.Stops) {
+		t.Fatalf("ReadPalette has %d stops, want %d", len(p.Stops), len(want.Stops))
+	}
+	for i, stop := range want.Stops {
+		if p.Stops[i] != stop {
+			t.Errorf("stop %d = %+v, want %+v", i, p.Stops[i], stop)
+		}
+	}
+}
+
+func TestReadPaletteErrors(t *testing.T) {
+	tests := map[string]string{
+		"not json":     `{"stops": [`,
+		"one stop":     `{"stops": [{"pos": 0, "color": "#000000"}]}`,
+		"out of range": `{"stops": [{"pos": 0, "color": "#000000"}, {"pos": 1.5, "color": "#ffffff"}]}`,
+		"bad color":    `{"stops": [{"pos": 0, "color": "black"}, {"pos": 1, "color": "#ffffff"}]}`,
+		"bad hex":      `{"stops": [{"pos": 0, "color": "#00zz00"}, {"pos": 1, "color": "#ffffff"}]}`,
+		"bad interior": `{"interior": "#fff", "stops": [{"pos": 0, "color": "#000000"}, {"pos": 1, "color": "#ffffff"}]}`,
+	}
+	for name, file := range tests {
+		if p, err := ReadPalette(strings.NewReader(file)); err == nil {
+			t.Errorf("%s: ReadPalette = %+v, want an error", name, p)
+		}
+	}
+}
+
+func TestPaletteColor(t *testing.T) {
+	red, blue := color.RGBA{255, 0, 0, 255}, color.RGBA{0, 0, 255, 255}
+	purple := color.RGBA{128, 0, 128, 255}
+	stops := []ColorStop{{0, red}, {1, blue}}
+	clamped := Palette{Stops: stops, Scale: 10}
+	cyclic := Palette{Stops: stops, Scale: 10, Cyclic: true}
+	shifted := Palette{Stops: stops, Scale: 10, Offset: 5}
+
+	tests := []struct {
+		name    string
+		palette Palette
+		v       float64
+		want    color.RGBA
+	}{
+		{"clamped start", clamped, 0, red},
+		{"clamped middle", clamped, 5, purple},
+		{"clamped end", clamped, 10, blue},
+		{"clamped below", clamped, -3, red},
+		{"clamped above", clamped, 25, blue},
+		{"cyclic start", cyclic, 0, red},
+		{"cyclic middle", cyclic, 5, purple},
+		{"cyclic wraps", cyclic, 15, purple},
+		{"cyclic wraps to start", cyclic, 20, red},
+		{"offset", shifted, 0, purple},
+		{"classic", ClassicPalette(), 3, escapeColor(3)},
+	}
+	for _, test := range tests {
+		if got := test.palette.Color(test.v); got != test.want {
+			t.Errorf("%s: Color(%v) = %v, want %v", test.name, test.v, got, test.want)
+		}
+	}
+}
+
+func TestInteriorColor(t *testing.T) {
+	interior := color.RGBA{1, 2, 3, 255}
+	m := NewMandelbrot(10, 10)
+	m.Palette = builtinPalettes["fire"]
+	m.Palette.Interior = interior
+	if got, _ := m.ColorAt(0, 100); got != interior {
+		t.Errorf("the origin is colored %v, want the interior color %v", got, interior)
+	}
+	if got, _ := m.ColorAt(2, 100); got == interior {
+		t.Errorf("an escaping point is colored with the interior color %v", got)
+	}
+}
@@ -0,0 +1,95 @@
+package mandelbrot
+
+import (
+	"math/big"
+	"math/cmplx"
+)
+
+/*
+Perturbation renders a deep zoom at float64 speed.
+
+Only the reference orbit Z_n, starting at the center of the view, is iterated with
+big.Float. Each pixel then follows the difference d_n = z_n - Z_n to the reference,
+which stays small enough for float64 :
+
+	d_n+1 = 2 Z_n d_n + d_n² + dc
+
+where dc is the offset from the pixel to the center (0 for a Julia set, whose pixels
+only change the starting point d_0).
+
+The float64 difference stops being accurate (a glitch) when z_n gets closer to 0 than
+d_n, and the reference is useless once it escaped. In both cases the pixel is rebased :
+it continues as its own difference to the start of the reference orbit, d = z_n - Z_0,
+and the reference is followed again from Z_0.
+*/
+
+// referenceOrbit is the orbit of the center of a deep zoom, rounded to complex128.
+type referenceOrbit struct {
+	z []complex128
+	// step is the size of a pixel in the complex plane
+	step float64
+}
+
+// computeReferenceOrbit iterates the center of the view of m in arbitrary precision, for at
+// most nbIteration iterations and one more past the bailout. The orbit always has a second
+// term, which the pixels rebased on its start follow even when the center escapes at once.
+func computeReferenceOrbit(m Mandelbrot, nbIteration int) *referenceOrbit {
+	d := m.Deep
+	newFloat := func() *big.Float { return new(big.Float).SetPrec(d.Precision) }
+
+	x, y := newFloat(), newFloat()
+	cx, cy := newFloat().Set(d.CenterX), newFloat().Set(d.CenterY)
+	if m.Julia {
+		x.Set(d.CenterX)
+		y.Set(d.CenterY)
+		cx.SetFloat64(real(m.JuliaC))
+		cy.SetFloat64(imag(m.JuliaC))
+	}
+
+	bailout := m.Coloring.Bailout()
+	orbit := &referenceOrbit{z: make([]complex128, 0, nbIteration+1)}
+	orbit.step, _ = newFloat().Quo(d.Scale, big.NewFloat(float64(m.Width))).Float64()
+	x2, y2, xy := newFloat(), newFloat(), newFloat()
+	for n := 0; n <= nbIteration; n++ {
+		fx, _ := x.Float64()
+		fy, _ := y.Float64()
+		orbit.z = append(orbit.z, complex(fx, fy))
+		if n > 0 && real(orbit.z[n])*real(orbit.z[n])+imag(orbit.z[n])*imag(orbit.z[n]) > bailout*bailout {
+			break
+		}
+		x2.Mul(x, x)
+		y2.Mul(y, y)
+		xy.Mul(x, y)
+		y.Add(xy, xy)
+		y.Add(y, cy)
+		x.Sub(x2, y2)
+		x.Add(x, cx)
+	}
+	return orbit
+}
+
+// escapeAt iterates the pixel at offset (dx, dy) from the center of the view by perturbation.
+func (r *referenceOrbit) escapeAt(dx, dy float64, julia bool, nbIteration int, bailout float64) Escape {
+	dc := complex(dx, dy)
+	var delta complex128
+	if julia {
+		delta, dc = dc, 0
+	}
+	limit := bailout * bailout
+	ref := 0
+	for n := 0; n < nbIteration; n++ {
+		z := r.z[ref] + delta
+		modulus2 := real(z)*real(z) + imag(z)*imag(z)
+		if modulus2 > limit {
+			return Escape{Iterations: n, Modulus: cmplx.Abs(z)}
+		}
+		// rebases on a glitch or when the reference has no next term
+		if modulus2 < real(delta)*real(delta)+imag(delta)*imag(delta) || ref == len(r.z)-1 {
+			delta = z - r.z[0]
+			ref = 0
+		}
+		delta = 2*r.z[ref]*delta + delta*delta + dc
+		ref++
+	}
+	return Escape{Iterations: nbIteration, Modulus: cmplx.Abs(r.z[ref] + delta)}
+}
@@ -0,0 +1,69 @@
+package mandelbrot
+
+import (
+	"context"
+	"testing"
+)
+
+// TestPerturbationMatchesExact compares perturbation to iterating every pixel in arbitrary precision.
+func TestPerturbationMatchesExact(t *testing.T) {
+	tests := []struct {
+		name                    string
+		m                       Mandelbrot
+		centerX, centerY, scale string
+		angle, skew             float64
+	}{
+		{"seahorse", NewMandelbrot(32, 24), "-0.743643887037158704752191506114774", "0.131825904205311970493132056385139", "1e-20", 0, 0},
+		{"rotated", NewMandelbrot(32, 24), "-0.743643887037158704752191506114774", "0.131825904205311970493132056385139", "1e-12", 30, 0.3},
+		// the reference escapes after about 300 iterations, the pixels inside the set rebase
+		{"escaping reference", NewMandelbrot(32, 24), "0.2501", "0", "1e-3", 0, 0},
+		{"julia", NewJulia(32, 24, complex(-0.8, 0.156)), "0.1", "0.2", "1e-15", 0, 0},
+		// the reference of a Julia set centered out of the bailout disk escapes at its start
+		{"escaping julia", NewJulia(16, 16, complex(-0.8, 0.156)), "2.1", "0", "1", 0, 0},
+	}
+	for _, test := range tests {
+		m := test.m
+		m.Angle, m.Skew = test.angle, test.skew
+		d, err := NewDeepZoom(test.centerX, test.centerY, test.scale, 128)
+		if err != nil {
+			t.Fatal(err)
+		}
+		exact := *d
+		exact.Exact = true
+		m.SetDeepZoom(&exact)
+		want, err := Compute(context.Background(), m, 2, 1000)
+		if err != nil {
+			t.Fatal(err)
+		}
+		m.SetDeepZoom(d)
+		got, err := Compute(context.Background(), m, 2, 1000)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if same := sameIterations(want, got); same < 0.99 {
+			t.Errorf("%s: %.2f%% of the pixels escape at the same iteration, want 99%%", test.name, 100*same)
+		}
+	}
+}
+
+// TestPerturbationRebases follows an orbit of the set on the reference of an escaping point.
+func TestPerturbationRebases(t *testing.T) {
+	d, err := NewDeepZoom("0.2501", "0", "1e-3", 128)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := NewMandelbrot(1, 1)
+	m.SetDeepZoom(d)
+	orbit := computeReferenceOrbit(m, 1000)
+	if len(orbit.z) > 1000 {
+		t.Fatalf("the reference orbit of 0.2501 did not escape")
+	}
+	/* 0.2499 belongs to the set, 0.2502 escapes shortly after the reference */
+	if e := orbit.escapeAt(-0.0002, 0, false, 1000, m.Coloring.Bailout()); e.Iterations != 1000 {
+		t.Errorf("0.2499 escapes at iteration %d, want it inside the set", e.Iterations)
+	}
+	want := NewMandelbrot(1, 1).EscapeAt(complex(0.2502, 0), 1000)
+	if e := orbit.escapeAt(0.0001, 0, false, 1000, m.Coloring.Bailout()); e.Iterations != want.Iterations {
+		t.Errorf("0.2502 escapes at iteration %d, want %d", e.Iterations, want.Iterations)
+	}
+}
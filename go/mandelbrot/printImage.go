@@ -1,129 +1,284 @@
 package mandelbrot
 
 import (
+	"context"
 	"fmt"
 	"image"
-	"image/color"
+	"image/draw"
 	"image/png"
+	"io"
 	"os"
 	"sync"
 )
 
 // PrintOnImage generates the Mandelbrot image using parallel processing.
-func PrintOnImage(m Mandelbrot, filePath string, numGoroutines, nbIterations int) error {
+// numWorkers is the size of the worker pool, runtime.NumCPU() when not positive.
+func PrintOnImage(m Mandelbrot, filePath string, numWorkers, nbIterations int) error {
 	/*
 		prints mandelbrot onto an image
-			using numGoroutines goroutines slicing the image into vertical slices
-			with a precision of nbIteration iterations
+			renders it with Render
+			then saves it in filePath
 	*/
-	// initial values
-	var wg sync.WaitGroup
-	rowsPerGoroutine := m.Height / numGoroutines
+	img, _, err := Render(context.Background(), m, numWorkers, nbIterations)
+	if err != nil {
+		return err
+	}
+	return SaveImage(img, filePath)
+}
 
-	// creates a list of rows to store the result of computations in each goroutine
-	rowList := make(chan [][]color.RGBA, numGoroutines)
-	rowOrders := make(chan int, numGoroutines)
+// RenderTo renders m like Render and writes it to w as a PNG image.
+// Nothing is written when the render fails or is cancelled.
+func RenderTo(ctx context.Context, w io.Writer, m Mandelbrot, numWorkers, nbIterations int) (RenderStats, error) {
+	img, stats, err := Render(ctx, m, numWorkers, nbIterations)
+	if err != nil {
+		return stats, err
+	}
+	return stats, WriteImage(w, img)
+}
 
-	for routineStep := 0; routineStep < numGoroutines; routineStep++ {
-		startRow := int(routineStep * rowsPerGoroutine)
-		endRow := int((routineStep + 1) * rowsPerGoroutine)
+// RenderStats reports how a render went.
+type RenderStats struct {
+	// Refined is the number of supersampled pixels.
+	Refined int
+}
 
-		if routineStep == numGoroutines-1 {
-			endRow = m.Height
-		}
+// Render computes the image of m using parallel processing.
+// When ctx is done before the end, the workers stop and Render returns the image computed so far,
+// transparent where the pixels are missing, with an error wrapping the error of ctx.
+func Render(ctx context.Context, m Mandelbrot, numWorkers, nbIterations int) (*image.RGBA, RenderStats, error) {
+	/*
+		colors every pixel with the palette of m as soon as its tile is computed
+		when supersampling, colors every sample and averages them instead
+		when adaptive, computes the escape of every pixel with Compute first
+			then only supersamples the pixels on an edge of the escape values
+	*/
+	var stats RenderStats
+	rendered, err := m.prepare(nbIterations)
+	if err != nil {
+		return nil, stats, err
+	}
 
-		wg.Add(1)
+	if m.Samples <= 1 {
+		img := image.NewRGBA(image.Rect(0, 0, m.Width, m.Height))
+		err = colorTiles(ctx, img, m, rendered, numWorkers, nbIterations)
+		return renderResult(ctx, img, stats, err)
+	}
+	if !m.Adaptive {
+		img := image.NewRGBA(image.Rect(0, 0, m.Width, m.Height))
+		stats.Refined, err = supersample(ctx, img, rendered, numWorkers, nbIterations, nil)
+		return renderResult(ctx, img, stats, err)
+	}
 
-		// starts a go routine to compute points from startRow to endRow
-		// it will compute the image in numGoroutine vertical sections
-		go ComputeOnSample(rowList, rowOrders, m, &wg, nbIterations, routineStep, startRow, endRow)
+	buffer, missing, err := compute(ctx, m, rendered, numWorkers, nbIterations)
+	img := Colorize(buffer, m.palette(), m.Coloring)
+	if err != nil {
+		for _, t := range missing {
+			draw.Draw(img, image.Rect(t.X0, t.Y0, t.X1, t.Y1), image.Transparent, image.Point{}, draw.Src)
+		}
+		return renderResult(ctx, img, stats, err)
+	}
+	edges := findEdges(buffer, m.Coloring, m.AdaptiveThreshold)
+	stats.Refined, err = supersample(ctx, img, rendered, numWorkers, nbIterations, edges)
+	return renderResult(ctx, img, stats, err)
+}
 
+// renderResult returns what Render returns for img and the error err of the workers:
+// the partial image when ctx stopped them, nothing for any other error.
+func renderResult(ctx context.Context, img *image.RGBA, stats RenderStats, err error) (*image.RGBA, RenderStats, error) {
+	if err == nil {
+		return img, stats, nil
 	}
+	if ctx.Err() != nil {
+		return img, stats, fmt.Errorf("render cancelled: %w", ctx.Err())
+	}
+	return nil, stats, err
+}
 
-	// waits termination of all goroutines
-	wg.Wait()
+// colorTiles colors every pixel of img, rendering m with its prepared counterpart rendered,
+// without keeping the escapes of the whole image: each worker computes a tile into a buffer
+// of its own, then colors the tile straight into img.
+func colorTiles(ctx context.Context, img *image.RGBA, m, rendered Mandelbrot, numWorkers, nbIterations int) error {
+	b := EscapeBuffer{Mandelbrot: m, MaxIterations: nbIterations}
+	palette := m.palette()
+	tiles := Tiles(m.Width, m.Height, TileSize)
+	progress := newProgressTracker(m.OnProgress, "computing", len(tiles))
 
-	SaveImage(rowList, rowOrders, m, numGoroutines, filePath)
-	return nil
+	// the tile buffers are reused from one tile to the next
+	buffers := sync.Pool{New: func() any { return new([TileSize * TileSize]Escape) }}
+	return runTiles(ctx, tiles, numWorkers, func(ctx context.Context, t Tile) error {
+		buffer := buffers.Get().(*[TileSize * TileSize]Escape)
+		defer buffers.Put(buffer)
+		rows := escapeRows{values: buffer[:t.Width()*t.Height()], x0: t.X0, y0: t.Y0, stride: t.Width()}
+		if err := rendered.computeTile(ctx, rows, nbIterations, t); err != nil {
+			return err
+		}
+		for y := t.Y0; y < t.Y1; y++ {
+			for x := t.X0; x < t.X1; x++ {
+				img.SetRGBA(x, y, b.color(*rows.at(x, y), palette, m.Coloring))
+			}
+		}
+		progress.tileDone()
+		return nil
+	})
 }
 
-func ComputeOnSample(rowList chan [][]color.RGBA, rowOrder chan int, m Mandelbrot, wg *sync.WaitGroup, nbIterations, routineStep, start, end int) error {
-	/*
-		computes mandelbrot on a sample from where the x and y coordinate varies like this :
-			x : from start to end
-			y : from 0 to width
-			lr : list of the rows of the image
-	*/
-	// ensures the waitgroup gets a return value after execution of this method
-	defer wg.Done()
-	colors := make([][]color.RGBA, end-start)
-
-	// Initialize each row in the 2D slice
-	for i := 0; i < end-start; i++ {
-		colors[i] = make([]color.RGBA, m.Width)
-	}
-
-	for i := 0; i < end-start; i++ {
-		for j := 0; j < m.Width; j++ {
-			c := complex(
-				float64(j)/float64(m.Width)*(m.XMax-m.XMin)+m.XMin,
-				float64(i+start)/float64(m.Height)*(m.YMax-m.YMin)+m.YMin,
-			)
-			err := error(nil)
-			colors[i][j], err = ColorConvergence(c, nbIterations)
-			// sets the pixel of coordinate (i, j) to color : color.
-			if err != nil {
-				return fmt.Errorf("tried to apply a color to a pixel out of image \n coordinate : (%v, %v) ", i, j)
-			}
+// Compute iterates every pixel of m using parallel processing and returns the raw escapes.
+// When ctx is done before the end, it returns the escapes computed so far, the others being zero,
+// with an error wrapping the error of ctx.
+func Compute(ctx context.Context, m Mandelbrot, numWorkers, nbIterations int) (EscapeBuffer, error) {
+	rendered, err := m.prepare(nbIterations)
+	if err != nil {
+		return EscapeBuffer{}, err
+	}
+	buffer, _, err := compute(ctx, m, rendered, numWorkers, nbIterations)
+	if err != nil {
+		if ctx.Err() != nil {
+			return buffer, fmt.Errorf("computation cancelled: %w", ctx.Err())
 		}
+		return EscapeBuffer{}, err
 	}
-	// send colors to channel
-	rowList <- colors
-	// sends index to channel
-	rowOrder <- routineStep
-	return nil
+	return buffer, nil
 }
 
-// SaveImage saves the generated Mandelbrot image as a PNG file.
-func SaveImage(rowList chan [][]color.RGBA, rowOrder chan int, m Mandelbrot, numGoroutines int, filePath string) error {
+// compute fills the escape buffer of m, iterating with its prepared counterpart rendered.
+// It stops at the first error of a worker, or when ctx is done,
+// and then also returns the tiles missing from the buffer.
+func compute(ctx context.Context, m, rendered Mandelbrot, numWorkers, nbIterations int) (EscapeBuffer, []Tile, error) {
 	/*
-		Prints the output of whats computed (which is inside m.Image) onto a
-			file in filePath path
+		computes the escapes of mandelbrot
+			cutting the image into tiles shared by a pool of numWorkers goroutines
+			with a precision of nbIteration iterations
+		the tiles do not overlap: each worker writes its escapes straight into the buffer
 	*/
-	file, err := os.Create(filePath)
+	buffer := NewEscapeBuffer(m, nbIterations)
+	tiles := Tiles(m.Width, m.Height, TileSize)
+	progress := newProgressTracker(m.OnProgress, "computing", len(tiles))
+
+	// done[i] is only written by the worker of tiles[i]
+	done := make([]bool, len(tiles))
+	columns := (m.Width + TileSize - 1) / TileSize
+	err := runTiles(ctx, tiles, numWorkers, func(ctx context.Context, t Tile) error {
+		err := ComputeOnSample(ctx, buffer.Values, rendered, nbIterations, t)
+		if err == nil {
+			done[t.Y0/TileSize*columns+t.X0/TileSize] = true
+			progress.tileDone()
+		}
+		return err
+	})
 	if err != nil {
-		return fmt.Errorf("could not create file: %v", err)
+		var missing []Tile
+		for i, t := range tiles {
+			if !done[i] {
+				missing = append(missing, t)
+			}
+		}
+		return buffer, missing, err
 	}
+	return buffer, nil, nil
+}
 
-	imageList := make([][]color.RGBA, m.Height)
-	for i := 0; i < m.Height; i++ {
-		imageList[i] = make([]color.RGBA, m.Width)
+// prepare checks that m can be rendered and computes what every pixel will need.
+func (m Mandelbrot) prepare(nbIterations int) (Mandelbrot, error) {
+	if err := m.validateKernel(); err != nil {
+		return m, err
+	}
+	if err := m.validateDeepZoom(); err != nil {
+		return m, err
 	}
+	return m.withReferenceOrbit(nbIterations), nil
+}
 
-	close(rowList)
-	close(rowOrder)
-	// need to recreate the image here
-	for val := range rowList {
-		index := <-rowOrder
-		for j := 0; j < len(val); j++ {
-			imageList[index*len(val)+j] = val[j]
-		}
+// ComputeOnSample computes the escapes of the pixels of the tile t into values,
+// the escapes of the whole image row by row.
+// It gives up between two rows of pixels when ctx is done, returning its error.
+func ComputeOnSample(ctx context.Context, values []Escape, m Mandelbrot, nbIterations int, t Tile) error {
+	if t.X0 < 0 || t.Y0 < 0 || t.X1 > m.Width || t.Y1 > m.Height || t.Width() <= 0 || t.Height() <= 0 {
+		return fmt.Errorf("tile %v out of the %dx%d image", t, m.Width, m.Height)
+	}
+	if len(values) != m.Width*m.Height {
+		return fmt.Errorf("%d escapes for a %dx%d image", len(values), m.Width, m.Height)
 	}
+	return m.computeTile(ctx, escapeRows{values: values, stride: m.Width}, nbIterations, t)
+}
 
-	image := image.NewRGBA(image.Rect(0, 0, m.Width, m.Height))
+// escapeRows holds the escapes of the pixels from (x0, y0) on, row by row with stride escapes per row.
+type escapeRows struct {
+	values         []Escape
+	x0, y0, stride int
+}
 
-	for i := 0; i < len(imageList); i++ {
-		for j := 0; j < len(imageList[i]); j++ {
-			image.Set(j, i, imageList[i][j])
+// at returns the escape of the pixel (x, y).
+func (r escapeRows) at(x, y int) *Escape {
+	return &r.values[(y-r.y0)*r.stride+x-r.x0]
+}
+
+// row returns the escapes of the pixels x0 to x1 (excluded) of the row y.
+func (r escapeRows) row(y, x0, x1 int) []Escape {
+	start := (y-r.y0)*r.stride - r.x0
+	return r.values[start+x0 : start+x1]
+}
+
+// computeTile computes the escapes of the pixels of the tile t into rows with the strategy and
+// the kernel of m. It gives up between two rows of pixels when ctx is done, returning its error.
+func (m Mandelbrot) computeTile(ctx context.Context, rows escapeRows, nbIterations int, t Tile) error {
+	if m.Strategy == Subdivision {
+		if err := ctx.Err(); err != nil {
+			return err
 		}
+		m.subdivide(rows, nbIterations, t)
+		return nil
 	}
 
-	// ensure the closure of the file before putting the pixels into it
-	defer file.Close()
-	err = png.Encode(file, image)
+	var points []complex128
+	if m.batchable() {
+		points = make([]complex128, t.Width())
+	}
+	for y := t.Y0; y < t.Y1; y++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if points != nil {
+			for x := t.X0; x < t.X1; x++ {
+				points[x-t.X0] = m.Point(float64(x)+0.5, float64(y)+0.5)
+			}
+			m.batchEscapes(points, rows.row(y, t.X0, t.X1), nbIterations)
+			continue
+		}
+		for x := t.X0; x < t.X1; x++ {
+			*rows.at(x, y) = m.pixelEscape(x, y, nbIterations)
+		}
+	}
+	return nil
+}
+
+// WriteImage encodes the generated Mandelbrot image to w in the PNG format.
+func WriteImage(w io.Writer, img image.Image) error {
+	err := png.Encode(w, img)
+	if err != nil {
+		return fmt.Errorf("could not encode image: %v", err)
+	}
+	return nil
+}
+
+// SaveImage saves the generated Mandelbrot image as a PNG file.
+func SaveImage(img image.Image, filePath string) error {
+	/*
+		Prints img onto a file in filePath path
+	*/
+	file, err := os.Create(filePath)
+	if err != nil {
+		return fmt.Errorf("could not create file: %v", err)
+	}
+	err = WriteImage(file, img)
+	if err != nil {
+		file.Close()
+		return err
+	}
 
+	// the pixels may only reach the disk when the file is closed
+	err = file.Close()
 	if err != nil {
-		return fmt.Errorf("could not encode image to file: %v", err)
+		return fmt.Errorf("could not write image to file: %v", err)
 	}
 
 	return nil
@@ -0,0 +1,413 @@
+package mandelbrot
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"sync"
+	"testing"
+	"time"
+)
+
+// workerCounts are the pool sizes compared against a single worker,
+// most of them dividing neither the width nor the height of the test images.
+var workerCounts = []int{0, 2, 3, 5, 7, 16, 100, 1000}
+
+// testSizes are image sizes smaller than, equal to and not multiple of TileSize.
+var testSizes = [][2]int{{1, 1}, {7, 13}, {32, 32}, {203, 117}, {97, 250}}
+
+func TestComputeIdenticalAcrossWorkers(t *testing.T) {
+	for _, size := range testSizes {
+		m := NewMandelbrot(size[0], size[1])
+		want, err := Compute(context.Background(), m, 1, 300)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, workers := range workerCounts {
+			got, err := Compute(context.Background(), m, workers, 300)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i := range want.Values {
+				if got.Values[i] != want.Values[i] {
+					t.Fatalf("%dx%d with %d workers: pixel (%d, %d) is %+v, want %+v",
+						m.Width, m.Height, workers, i%m.Width, i/m.Width, got.Values[i], want.Values[i])
+				}
+			}
+		}
+	}
+}
+
+func TestRenderIdenticalAcrossWorkers(t *testing.T) {
+	plain := NewMandelbrot(150, 77)
+	smooth := NewFractal(101, 67, BurningShip{})
+	smooth.Coloring = Smooth
+	smooth.Palette, _ = PaletteByName("fire")
+	supersampled := NewMandelbrot(61, 45)
+	supersampled.Samples = 3
+	adaptive := NewJulia(90, 51, complex(-0.8, 0.156))
+	adaptive.Samples = 2
+	adaptive.Adaptive = true
+
+	for name, m := range map[string]Mandelbrot{"plain": plain, "smooth": smooth, "supersampled": supersampled, "adaptive": adaptive} {
+		want, wantStats, err := Render(context.Background(), m, 1, 200)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, workers := range workerCounts {
+			got, stats, err := Render(context.Background(), m, workers, 200)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got.Pix, want.Pix) {
+				t.Errorf("%s with %d workers: image differs from a single worker", name, workers)
+			}
+			if stats != wantStats {
+				t.Errorf("%s with %d workers: stats %+v, want %+v", name, workers, stats, wantStats)
+			}
+		}
+	}
+}
+
+func TestRenderCancelled(t *testing.T) {
+	m := NewMandelbrot(300, 200)
+	m.Samples = 4
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	img, _, err := Render(ctx, m, 4, 1000)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("got error %v, want a cancellation", err)
+	}
+	if img == nil || img.Bounds() != image.Rect(0, 0, m.Width, m.Height) {
+		t.Fatal("no partial image")
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	m.Samples = 1
+	start := time.Now()
+	buffer, err := Compute(ctx, m, 2, 1000000)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got error %v, want a deadline", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Compute took %v to stop", elapsed)
+	}
+	if len(buffer.Values) != m.Width*m.Height {
+		t.Error("no partial buffer")
+	}
+}
+
+func TestRenderTo(t *testing.T) {
+	m := NewJulia(90, 51, complex(-0.8, 0.156))
+	want, _, err := Render(context.Background(), m, 2, 200)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	if _, err := RenderTo(context.Background(), &out, m, 2, 200); err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := png.Decode(&out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// an opaque image decodes to RGBA pixels
+	if got, ok := decoded.(*image.RGBA); !ok || got.Bounds() != want.Bounds() || !bytes.Equal(got.Pix, want.Pix) {
+		t.Error("written image differs from Render")
+	}
+
+	// nothing is written for a failed or cancelled render
+	d, err := NewDeepZoom("0", "0", "1e-20", 128)
+	if err != nil {
+		t.Fatal(err)
+	}
+	invalid := NewFractal(40, 30, BurningShip{})
+	invalid.SetDeepZoom(d)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for name, run := range map[string]func(w *bytes.Buffer) error{
+		"invalid": func(w *bytes.Buffer) error {
+			_, err := RenderTo(context.Background(), w, invalid, 2, 200)
+			return err
+		},
+		"cancelled": func(w *bytes.Buffer) error {
+			_, err := RenderTo(ctx, w, m, 2, 200)
+			return err
+		},
+	} {
+		var out bytes.Buffer
+		if err := run(&out); err == nil {
+			t.Errorf("%s: no error", name)
+		}
+		if out.Len() != 0 {
+			t.Errorf("%s: %d bytes written", name, out.Len())
+		}
+	}
+}
+
+func TestRenderProgress(t *testing.T) {
+	m := NewMandelbrot(100, 70)
+	m.Samples = 2
+	m.Adaptive = true
+	var reports []Progress
+	m.OnProgress = func(p Progress) {
+		reports = append(reports, p)
+	}
+	if _, _, err := Render(context.Background(), m, 3, 100); err != nil {
+		t.Fatal(err)
+	}
+
+	tiles := len(Tiles(m.Width, m.Height, TileSize))
+	done := map[string]int{}
+	for _, p := range reports {
+		if p.Total != tiles || p.Done != done[p.Stage] && p.Done != done[p.Stage]+1 {
+			t.Fatalf("unexpected progress %+v after %d tiles", p, done[p.Stage])
+		}
+		done[p.Stage] = p.Done
+	}
+	if done["computing"] != tiles || done["supersampling"] != tiles {
+		t.Errorf("stages ended at %v, want %d tiles each", done, tiles)
+	}
+}
+
+// benchmarkView is the default view at a quarter of the 3840x2160 resolution of the CLI.
+func benchmarkView() Mandelbrot {
+	return NewMandelbrot(960, 540)
+}
+
+const benchmarkIterations = 200
+
+// computeThroughChannel computes m the way compute did before the workers shared the buffer:
+// each tile is computed in its own slice, sent over a channel and copied into the buffer.
+func computeThroughChannel(m Mandelbrot, numWorkers, nbIterations int) EscapeBuffer {
+	buffer := NewEscapeBuffer(m, nbIterations)
+	type tileEscapes struct {
+		Tile
+		values []Escape
+	}
+	tileList := make(chan tileEscapes, Workers(numWorkers))
+	go func() {
+		runTiles(context.Background(), Tiles(m.Width, m.Height, TileSize), numWorkers, func(_ context.Context, t Tile) error {
+			values := make([]Escape, t.Width()*t.Height())
+			for y := t.Y0; y < t.Y1; y++ {
+				for x := t.X0; x < t.X1; x++ {
+					values[(y-t.Y0)*t.Width()+x-t.X0] = m.pixelEscape(x, y, nbIterations)
+				}
+			}
+			tileList <- tileEscapes{t, values}
+			return nil
+		})
+		close(tileList)
+	}()
+	for computed := range tileList {
+		t := computed.Tile
+		for y := t.Y0; y < t.Y1; y++ {
+			copy(buffer.Values[y*m.Width+t.X0:y*m.Width+t.X1], computed.values[(y-t.Y0)*t.Width():])
+		}
+	}
+	return buffer
+}
+
+func TestComputeThroughChannelMatches(t *testing.T) {
+	m := NewMandelbrot(203, 117)
+	want := computeThroughChannel(m, 3, 300)
+	got, err := Compute(context.Background(), m, 3, 300)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range want.Values {
+		if got.Values[i] != want.Values[i] {
+			t.Fatalf("pixel %d is %+v, want %+v", i, got.Values[i], want.Values[i])
+		}
+	}
+}
+
+func BenchmarkCompute(b *testing.B) {
+	m := benchmarkView()
+	b.Run("shared-buffer", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := Compute(context.Background(), m, 0, benchmarkIterations); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("channel", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			computeThroughChannel(m, 0, benchmarkIterations)
+		}
+	})
+}
+
+// renderThroughBands renders m the way PrintOnImage did before tiles and escape buffers:
+// each goroutine colors a band of rows into its own [][]color.RGBA, sent over a channel
+// with its index, then the bands are copied into a second [][]color.RGBA and set in the image.
+func renderThroughBands(m Mandelbrot, numGoroutines, nbIterations int) *image.RGBA {
+	var wg sync.WaitGroup
+	rowsPerGoroutine := m.Height / numGoroutines
+	rowList := make(chan [][]color.RGBA, numGoroutines)
+	rowOrders := make(chan int, numGoroutines)
+	for routineStep := 0; routineStep < numGoroutines; routineStep++ {
+		start, end := routineStep*rowsPerGoroutine, (routineStep+1)*rowsPerGoroutine
+		if routineStep == numGoroutines-1 {
+			end = m.Height
+		}
+		wg.Add(1)
+		go func(routineStep, start, end int) {
+			defer wg.Done()
+			colors := make([][]color.RGBA, end-start)
+			for i := range colors {
+				colors[i] = make([]color.RGBA, m.Width)
+				for j := range colors[i] {
+					c := complex(
+						float64(j)/float64(m.Width)*(m.XMax-m.XMin)+m.XMin,
+						float64(i+start)/float64(m.Height)*(m.YMax-m.YMin)+m.YMin,
+					)
+					colors[i][j], _ = ColorConvergence(c, nbIterations)
+				}
+			}
+			rowList <- colors
+			rowOrders <- routineStep
+		}(routineStep, start, end)
+	}
+	wg.Wait()
+
+	imageList := make([][]color.RGBA, m.Height)
+	for i := range imageList {
+		imageList[i] = make([]color.RGBA, m.Width)
+	}
+	close(rowList)
+	close(rowOrders)
+	for val := range rowList {
+		index := <-rowOrders
+		for j := range val {
+			imageList[index*len(val)+j] = val[j]
+		}
+	}
+	img := image.NewRGBA(image.Rect(0, 0, m.Width, m.Height))
+	for i := range imageList {
+		for j := range imageList[i] {
+			img.Set(j, i, imageList[i][j])
+		}
+	}
+	return img
+}
+
+func BenchmarkRender(b *testing.B) {
+	m := benchmarkView()
+	b.Run("shared-image", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, _, err := Render(context.Background(), m, 0, benchmarkIterations); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("bands", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			renderThroughBands(m, Workers(0), benchmarkIterations)
+		}
+	})
+}
+
+func TestRenderProgressiveEndsLikeRender(t *testing.T) {
+	plain := NewMandelbrot(150, 77)
+	adaptive := NewJulia(90, 51, complex(-0.8, 0.156))
+	adaptive.Samples = 2
+	adaptive.Adaptive = true
+	supersampled := NewMandelbrot(61, 45)
+	supersampled.Samples = 2
+	batch := NewMandelbrot(150, 77)
+	batch.Kernel = Batch
+	batchJulia := NewJulia(90, 51, complex(-0.8, 0.156))
+	batchJulia.Kernel = Batch
+	fast := NewFractal(101, 67, BurningShip{})
+	fast.Kernel = Fast
+
+	for name, m := range map[string]Mandelbrot{
+		"plain": plain, "adaptive": adaptive, "supersampled": supersampled,
+		"batch": batch, "batch julia": batchJulia, "fast": fast,
+	} {
+		want, wantStats, err := Render(context.Background(), m, 1, 200)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var passes []Pass
+		got, stats, err := RenderProgressive(context.Background(), m, 3, 200, func(p Pass, img *image.RGBA) error {
+			passes = append(passes, p)
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got.Pix, want.Pix) || stats != wantStats {
+			t.Errorf("%s: the progressive render differs from Render", name)
+		}
+		if last := passes[len(passes)-1]; len(passes) != last.Passes || last.Number != last.Passes || last.Step != 1 {
+			t.Errorf("%s: passes %+v", name, passes)
+		}
+	}
+}
+
+func TestRenderProgressiveRefusesSubdivision(t *testing.T) {
+	m := NewMandelbrot(64, 64)
+	m.Strategy = Subdivision
+	_, _, err := RenderProgressive(context.Background(), m, 1, 200, func(Pass, *image.RGBA) error {
+		t.Error("a pass of a subdivision render was painted")
+		return nil
+	})
+	if err == nil {
+		t.Error("RenderProgressive rendered with the subdivision strategy, want an error")
+	}
+}
+
+func TestRenderProgressiveCancelled(t *testing.T) {
+	m := NewMandelbrot(100, 60)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	// the second pass starts cancelled, none of its tiles is finished
+	img, _, err := RenderProgressive(ctx, m, 2, 200, func(p Pass, img *image.RGBA) error {
+		cancel()
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("RenderProgressive returned %v, want a cancelled render", err)
+	}
+	for y := 0; y < m.Height; y++ {
+		for x := 0; x < m.Width; x++ {
+			if c := img.RGBAAt(x, y); c.A != 0 {
+				t.Fatalf("pixel (%d, %d) of an unfinished tile is %v, want it transparent", x, y, c)
+			}
+		}
+	}
+}
+
+func TestRenderProgressiveFirstPass(t *testing.T) {
+	m := NewMandelbrot(100, 60)
+	full, _, err := Render(context.Background(), m, 1, 200)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stop := errors.New("stop")
+	_, _, err = RenderProgressive(context.Background(), m, 2, 200, func(p Pass, img *image.RGBA) error {
+		// every pixel has the color of the top left corner of its 8x8 block
+		for y := 0; y < m.Height; y++ {
+			for x := 0; x < m.Width; x++ {
+				if img.RGBAAt(x, y) != full.RGBAAt(x-x%8, y-y%8) {
+					t.Fatalf("pixel (%d, %d) of the first pass is %v, want %v", x, y, img.RGBAAt(x, y), full.RGBAAt(x-x%8, y-y%8))
+				}
+			}
+		}
+		return stop
+	})
+	if err != stop {
+		t.Errorf("got error %v, want the error of the pass", err)
+	}
+}
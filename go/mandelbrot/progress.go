@@ -0,0 +1,65 @@
+package mandelbrot
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Progress reports how far a stage of a render went, in tiles.
+type Progress struct {
+	// Stage is "computing" while the escapes are computed, "supersampling" while pixels are supersampled,
+	// and "pass <i>/<n>" during the i-th of the n passes of RenderProgressive.
+	Stage       string
+	Done, Total int
+	// Elapsed is the time spent in the stage, ETA the time it should still take at the same pace.
+	Elapsed, ETA time.Duration
+}
+
+// Percent returns the completed fraction of the stage, between 0 and 100.
+func (p Progress) Percent() float64 {
+	if p.Total == 0 {
+		return 100
+	}
+	return 100 * float64(p.Done) / float64(p.Total)
+}
+
+// String formats p as "<stage> <done>/<total> tiles (<percent>%), ETA <eta>".
+func (p Progress) String() string {
+	return fmt.Sprintf("%s %d/%d tiles (%.1f%%), ETA %v", p.Stage, p.Done, p.Total, p.Percent(), p.ETA.Round(time.Second))
+}
+
+// progressTracker counts the tiles of a stage done by the workers and reports them to onProgress,
+// one call at a time.
+type progressTracker struct {
+	mu         sync.Mutex
+	onProgress func(Progress)
+	stage      string
+	start      time.Time
+	done       int
+	total      int
+}
+
+// newProgressTracker starts the stage of total tiles, reporting to onProgress.
+// It returns nil, which tracks nothing, when onProgress is nil.
+func newProgressTracker(onProgress func(Progress), stage string, total int) *progressTracker {
+	if onProgress == nil {
+		return nil
+	}
+	p := &progressTracker{onProgress: onProgress, stage: stage, start: time.Now(), total: total}
+	onProgress(Progress{Stage: stage, Total: total})
+	return p
+}
+
+// tileDone records a tile done and reports it.
+func (p *progressTracker) tileDone() {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.done++
+	elapsed := time.Since(p.start)
+	eta := time.Duration(float64(elapsed) / float64(p.done) * float64(p.total-p.done))
+	p.onProgress(Progress{Stage: p.stage, Done: p.done, Total: p.total, Elapsed: elapsed, ETA: eta})
+}
@@ -0,0 +1,136 @@
+package mandelbrot
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+)
+
+// progressiveSteps are the distances between the pixels iterated by the passes of RenderProgressive.
+// TileSize is a multiple of each of them, so that the blocks of a pass never cross two tiles.
+var progressiveSteps = []int{8, 4, 2, 1}
+
+// Pass describes an intermediate image of RenderProgressive.
+type Pass struct {
+	// Number counts the passes from 1 to Passes.
+	Number, Passes int
+	// Step is the side of the blocks of pixels colored like their top left pixel, 1 in the final image.
+	Step int
+}
+
+// RenderProgressive renders m like Render, but in passes: the first one only iterates every 8th pixel
+// of every 8th row and paints the 8x8 block below and on the right of it, then each pass halves the
+// step (8, 4, 2 then 1), only iterating the pixels not iterated yet, until every pixel is.
+// The supersampling, if any, is an additional pass.
+// onPass is called with the image after each pass: it is painted over by the next one and must be
+// copied to be kept. An error returned by onPass stops the render and is returned.
+// When ctx is done before the end, RenderProgressive returns the image of the interrupted pass,
+// transparent on the tiles that pass did not finish, with an error wrapping the error of ctx.
+// The Subdivision strategy, which needs the border of a rectangle before its inside, cannot
+// render a pass and is refused.
+func RenderProgressive(ctx context.Context, m Mandelbrot, numWorkers, nbIterations int, onPass func(p Pass, img *image.RGBA) error) (*image.RGBA, RenderStats, error) {
+	var stats RenderStats
+	if m.Strategy == Subdivision {
+		return nil, stats, fmt.Errorf("progressive renders do not support the %v strategy", m.Strategy)
+	}
+	rendered, err := m.prepare(nbIterations)
+	if err != nil {
+		return nil, stats, err
+	}
+
+	passes := len(progressiveSteps)
+	if m.Samples > 1 {
+		passes++
+	}
+	buffer := NewEscapeBuffer(m, nbIterations)
+	palette := m.palette()
+	img := image.NewRGBA(image.Rect(0, 0, m.Width, m.Height))
+	tiles := Tiles(m.Width, m.Height, TileSize)
+
+	for i, step := range progressiveSteps {
+		progress := newProgressTracker(m.OnProgress, fmt.Sprintf("pass %d/%d", i+1, passes), len(tiles))
+		// done[i] is only written by the worker of tiles[i]
+		done := make([]bool, len(tiles))
+		columns := (m.Width + TileSize - 1) / TileSize
+		err = runTiles(ctx, tiles, numWorkers, func(ctx context.Context, t Tile) error {
+			xs := make([]int, 0, t.Width())
+			for y := t.Y0; y < t.Y1; y += step {
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+				xs = xs[:0]
+				for x := t.X0; x < t.X1; x += step {
+					// the pixels on the grid of the previous pass are already iterated
+					if i == 0 || x%(2*step) != 0 || y%(2*step) != 0 {
+						xs = append(xs, x)
+					}
+				}
+				row := buffer.Values[y*m.Width : (y+1)*m.Width]
+				rendered.iterateRow(row, xs, y, nbIterations)
+				for _, x := range xs {
+					fillBlock(img, x, y, min(x+step, t.X1), min(y+step, t.Y1), buffer.color(row[x], palette, m.Coloring))
+				}
+			}
+			done[t.Y0/TileSize*columns+t.X0/TileSize] = true
+			progress.tileDone()
+			return nil
+		})
+		if err != nil {
+			for j, t := range tiles {
+				if !done[j] {
+					draw.Draw(img, image.Rect(t.X0, t.Y0, t.X1, t.Y1), image.Transparent, image.Point{}, draw.Src)
+				}
+			}
+			return renderResult(ctx, img, stats, err)
+		}
+		if err := onPass(Pass{Number: i + 1, Passes: passes, Step: step}, img); err != nil {
+			return nil, stats, err
+		}
+	}
+
+	if m.Samples > 1 {
+		var edges []bool
+		if m.Adaptive {
+			edges = findEdges(buffer, m.Coloring, m.AdaptiveThreshold)
+		}
+		stats.Refined, err = supersample(ctx, img, rendered, numWorkers, nbIterations, edges)
+		if err != nil {
+			return renderResult(ctx, img, stats, err)
+		}
+		if err := onPass(Pass{Number: passes, Passes: passes, Step: 1}, img); err != nil {
+			return nil, stats, err
+		}
+	}
+	return img, stats, nil
+}
+
+// iterateRow computes the escapes of the pixels xs of the row y into row, the escapes of the whole row,
+// by batches when m is batchable.
+func (m Mandelbrot) iterateRow(row []Escape, xs []int, y, nbIterations int) {
+	if !m.batchable() {
+		for _, x := range xs {
+			row[x] = m.pixelEscape(x, y, nbIterations)
+		}
+		return
+	}
+	points := make([]complex128, len(xs))
+	for i, x := range xs {
+		points[i] = m.Point(float64(x)+0.5, float64(y)+0.5)
+	}
+	escapes := make([]Escape, len(xs))
+	m.batchEscapes(points, escapes, nbIterations)
+	for i, x := range xs {
+		row[x] = escapes[i]
+	}
+}
+
+// fillBlock paints the pixels [x0, x1) x [y0, y1) of img with c.
+func fillBlock(img *image.RGBA, x0, y0, x1, y1 int, c color.RGBA) {
+	for y := y0; y < y1; y++ {
+		for x := x0; x < x1; x++ {
+			img.SetRGBA(x, y, c)
+		}
+	}
+}
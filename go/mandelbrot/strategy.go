@@ -0,0 +1,149 @@
+package mandelbrot
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Strategy selects which pixels of a tile are actually iterated.
+type Strategy int
+
+const (
+	// BruteForce iterates every pixel.
+	BruteForce Strategy = iota
+	// Subdivision is the Mariani-Silver algorithm: the border of a rectangle is iterated first,
+	// its inside is filled without iterating when the whole border escapes the same way,
+	// and it is cut in two halves otherwise. A rectangle whose border is uniform usually is
+	// uniform too (the set is connected), but thin details crossing it without touching its
+	// border are lost, so the result may differ slightly from BruteForce.
+	Subdivision
+)
+
+func (s Strategy) String() string {
+	switch s {
+	case BruteForce:
+		return "bruteforce"
+	case Subdivision:
+		return "subdivision"
+	}
+	return fmt.Sprintf("Strategy(%d)", int(s))
+}
+
+// StrategyByName returns the strategy called name (bruteforce or subdivision).
+func StrategyByName(name string) (Strategy, error) {
+	switch strings.ToLower(name) {
+	case "", "bruteforce":
+		return BruteForce, nil
+	case "subdivision", "mariani-silver":
+		return Subdivision, nil
+	}
+	return BruteForce, fmt.Errorf("unknown strategy %q", name)
+}
+
+// minSubdivision is the area under which a rectangle is iterated pixel by pixel
+// rather than cut again.
+const minSubdivision = 16
+
+// subdivide computes the escapes of the tile t into rows with the Subdivision strategy.
+// With the Batch kernel, the lines of pixels it iterates are computed by batchEscapes.
+func (m Mandelbrot) subdivide(rows escapeRows, nbIterations int, t Tile) {
+	s := subdivision{m: m, rows: rows, nbIterations: nbIterations}
+	if m.batchable() {
+		s.points = make([]complex128, max(t.Width(), t.Height()))
+		s.escapes = make([]Escape, len(s.points))
+	}
+	s.computeLine(t.X0, t.Y0, 1, 0, t.Width())
+	s.computeLine(t.X0, t.Y1-1, 1, 0, t.Width())
+	s.computeLine(t.X0, t.Y0+1, 0, 1, t.Height()-2)
+	s.computeLine(t.X1-1, t.Y0+1, 0, 1, t.Height()-2)
+	s.fill(t.X0, t.Y0, t.X1-1, t.Y1-1)
+}
+
+// subdivision is the state of the Subdivision strategy on a tile.
+type subdivision struct {
+	m            Mandelbrot
+	rows         escapeRows
+	nbIterations int
+	// points and escapes hold a line of pixels for batchEscapes, nil when m is not batchable
+	points  []complex128
+	escapes []Escape
+}
+
+// at returns the escape of the pixel (x, y).
+func (s subdivision) at(x, y int) *Escape {
+	return s.rows.at(x, y)
+}
+
+// computeLine iterates the count pixels from (x, y) on, moving by (dx, dy) from one to the next.
+func (s subdivision) computeLine(x, y, dx, dy, count int) {
+	if s.points == nil {
+		for i := 0; i < count; i++ {
+			*s.at(x+i*dx, y+i*dy) = s.m.pixelEscape(x+i*dx, y+i*dy, s.nbIterations)
+		}
+		return
+	}
+	if count <= 0 {
+		return
+	}
+	for i := 0; i < count; i++ {
+		s.points[i] = s.m.Point(float64(x+i*dx)+0.5, float64(y+i*dy)+0.5)
+	}
+	s.m.batchEscapes(s.points[:count], s.escapes[:count], s.nbIterations)
+	for i, e := range s.escapes[:count] {
+		*s.at(x+i*dx, y+i*dy) = e
+	}
+}
+
+// same tells whether a and b get the same color: both inside the set,
+// or escaped at the same iteration when the coloring only depends on it.
+func (s subdivision) same(a, b Escape) bool {
+	if a.Iterations == s.nbIterations || b.Iterations == s.nbIterations {
+		return a.Iterations == b.Iterations
+	}
+	return s.m.Coloring == Banded && a.Iterations == b.Iterations
+}
+
+// fill computes the inside of the rectangle whose corners are (x0, y0) and (x1, y1),
+// its border being already computed.
+func (s subdivision) fill(x0, y0, x1, y1 int) {
+	if x1-x0 < 2 || y1-y0 < 2 {
+		return // no inside
+	}
+
+	first := *s.at(x0, y0)
+	uniform := true
+	for x := x0; x <= x1 && uniform; x++ {
+		uniform = s.same(*s.at(x, y0), first) && s.same(*s.at(x, y1), first)
+	}
+	for y := y0 + 1; y < y1 && uniform; y++ {
+		uniform = s.same(*s.at(x0, y), first) && s.same(*s.at(x1, y), first)
+	}
+	if uniform {
+		for y := y0 + 1; y < y1; y++ {
+			for x := x0 + 1; x < x1; x++ {
+				*s.at(x, y) = first
+			}
+		}
+		return
+	}
+
+	if (x1-x0-1)*(y1-y0-1) <= minSubdivision {
+		for y := y0 + 1; y < y1; y++ {
+			s.computeLine(x0+1, y, 1, 0, x1-x0-1)
+		}
+		return
+	}
+
+	// cuts the longest side in two, the line between both halves is a border of each
+	if x1-x0 >= y1-y0 {
+		middle := (x0 + x1) / 2
+		s.computeLine(middle, y0+1, 0, 1, y1-y0-1)
+		s.fill(x0, y0, middle, y1)
+		s.fill(middle, y0, x1, y1)
+	} else {
+		middle := (y0 + y1) / 2
+		s.computeLine(x0+1, middle, 1, 0, x1-x0-1)
+		s.fill(x0, y0, x1, middle)
+		s.fill(x0, middle, x1, y1)
+	}
+}
@@ -0,0 +1,136 @@
+package mandelbrot
+
+import (
+	"context"
+	"testing"
+)
+
+// subdivisionDifference returns the fraction of the pixels of m which Subdivision
+// does not color like BruteForce, and the fraction it did not iterate.
+func subdivisionDifference(t testing.TB, m Mandelbrot, nbIterations int) (differ, skipped float64) {
+	want, err := Compute(context.Background(), m, 1, nbIterations)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.Strategy = Subdivision
+	iterated := 0
+	counting := m
+	counting.Formula = countingFormula{count: &iterated}
+	got, err := Compute(context.Background(), m, 1, nbIterations)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Compute(context.Background(), counting, 1, nbIterations); err != nil {
+		t.Fatal(err)
+	}
+
+	different := 0
+	for i, e := range want.Values {
+		g := got.Values[i]
+		if want.Inside(e) != got.Inside(g) || !want.Inside(e) && want.Value(e, m.Coloring) != got.Value(g, m.Coloring) {
+			different++
+		}
+	}
+	total := float64(len(want.Values))
+	return float64(different) / total, 1 - float64(iterated)/total
+}
+
+// countingFormula is Quadratic, counting the points it starts iterating.
+type countingFormula struct {
+	Quadratic
+	count *int
+}
+
+func (f countingFormula) Iterate(z, c complex128) complex128 {
+	if z == 0 {
+		*f.count++
+	}
+	return z*z + c
+}
+
+func TestSubdivisionDifference(t *testing.T) {
+	seahorse := NewMandelbrot(320, 180)
+	seahorse.SetView(View{CenterX: -0.745, CenterY: 0.113, Scale: 0.01})
+	smooth := NewMandelbrot(320, 180)
+	smooth.Coloring = Smooth
+	elephants := NewMandelbrot(320, 180)
+	elephants.SetView(View{CenterX: 0.275, CenterY: 0.006, Scale: 0.02})
+
+	// maximum fraction of differing pixels accepted for each view
+	views := []struct {
+		name      string
+		m         Mandelbrot
+		maxDiffer float64
+	}{
+		{"default", NewMandelbrot(480, 270), 0.001},
+		{"smooth", smooth, 0.001},
+		{"seahorse", seahorse, 0.01},
+		{"elephants", elephants, 0.01},
+	}
+	for _, v := range views {
+		differ, skipped := subdivisionDifference(t, v.m, 1000)
+		t.Logf("%s: %.4f%% of the pixels differ, %.1f%% were not iterated", v.name, 100*differ, 100*skipped)
+		if differ > v.maxDiffer {
+			t.Errorf("%s: %.4f%% of the pixels differ from the brute force render, want at most %.4f%%", v.name, 100*differ, 100*v.maxDiffer)
+		}
+	}
+}
+
+func TestSubdivisionIdenticalAcrossWorkers(t *testing.T) {
+	m := NewMandelbrot(203, 117)
+	m.Strategy = Subdivision
+	want, err := Compute(context.Background(), m, 1, 300)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := Compute(context.Background(), m, 5, 300)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range want.Values {
+		if got.Values[i] != want.Values[i] {
+			t.Fatalf("pixel %d is %+v with 5 workers, %+v with 1", i, got.Values[i], want.Values[i])
+		}
+	}
+}
+
+// TestSubdivisionBatch checks that Subdivision iterates the same pixels with the Batch kernel,
+// which computes its lines of pixels at once, as with the Reference kernel.
+func TestSubdivisionBatch(t *testing.T) {
+	for name, m := range kernelViews() {
+		m.Strategy = Subdivision
+		want, err := Compute(context.Background(), m, 1, 500)
+		if err != nil {
+			t.Fatal(err)
+		}
+		m.Kernel = Batch
+		got, err := Compute(context.Background(), m, 1, 500)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, e := range want.Values {
+			g := got.Values[i]
+			if g.Iterations != e.Iterations || !want.Inside(e) && g.Modulus != e.Modulus {
+				t.Errorf("%s: pixel (%d, %d) escapes as %+v, want %+v", name, i%m.Width, i/m.Width, g, e)
+				break
+			}
+		}
+	}
+}
+
+func BenchmarkStrategy(b *testing.B) {
+	for _, strategy := range []Strategy{BruteForce, Subdivision} {
+		for _, kernel := range []Kernel{Reference, Batch} {
+			m := benchmarkView()
+			m.Strategy = strategy
+			m.Kernel = kernel
+			b.Run(strategy.String()+"/"+kernel.String(), func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					if _, err := Compute(context.Background(), m, 0, 1000); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
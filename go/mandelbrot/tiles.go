@@ -0,0 +1,105 @@
+package mandelbrot
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sync"
+)
+
+// TileSize is the side, in pixels, of the tiles an image is cut into for rendering.
+// Small tiles keep the workers balanced whatever the view: a worker done with a cheap
+// tile just takes the next one while another one is still inside the set.
+const TileSize = 32
+
+// Tile is the rectangle of pixels [X0, X1) x [Y0, Y1) of an image.
+type Tile struct {
+	X0, Y0, X1, Y1 int
+}
+
+// Width returns the number of columns of t.
+func (t Tile) Width() int {
+	return t.X1 - t.X0
+}
+
+// Height returns the number of rows of t.
+func (t Tile) Height() int {
+	return t.Y1 - t.Y0
+}
+
+// Tiles cuts an image of width*height pixels into tiles of size*size pixels, row by row.
+// The tiles of the last row and column are smaller when size does not divide the image.
+func Tiles(width, height, size int) []Tile {
+	var tiles []Tile
+	for y := 0; y < height; y += size {
+		for x := 0; x < width; x += size {
+			tiles = append(tiles, Tile{X0: x, Y0: y, X1: min(x+size, width), Y1: min(y+size, height)})
+		}
+	}
+	return tiles
+}
+
+// Workers returns the size of the worker pool: numWorkers, or runtime.NumCPU() when it is not positive.
+func Workers(numWorkers int) int {
+	if numWorkers <= 0 {
+		return runtime.NumCPU()
+	}
+	return numWorkers
+}
+
+// runTiles hands the tiles out to a pool of numWorkers goroutines (see Workers):
+// each worker takes the next tile from the queue as soon as it is done with the previous one.
+// The first error returned by work, or panic raised in it, cancels the tiles not started yet
+// and is returned once every worker has stopped, like the error of parent if it is done first.
+// work is given a context cancelled in both cases, which it should check while it works on a tile.
+func runTiles(parent context.Context, tiles []Tile, numWorkers int, work func(ctx context.Context, t Tile) error) error {
+	ctx, cancel := context.WithCancel(parent)
+	defer cancel()
+
+	queue := make(chan Tile, len(tiles))
+	for _, t := range tiles {
+		queue <- t
+	}
+	close(queue)
+
+	var firstErr error
+	var once sync.Once
+	fail := func(err error) {
+		once.Do(func() {
+			firstErr = err
+			cancel()
+		})
+	}
+
+	var wg sync.WaitGroup
+	for worker := 0; worker < min(Workers(numWorkers), len(tiles)); worker++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for t := range queue {
+				if ctx.Err() != nil {
+					return
+				}
+				if err := runTile(ctx, work, t); err != nil {
+					fail(err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if firstErr != nil {
+		return firstErr
+	}
+	return parent.Err()
+}
+
+// runTile calls work on t, turning a panic into an error so that it does not bring the whole program down.
+func runTile(ctx context.Context, work func(ctx context.Context, t Tile) error, t Tile) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("worker panicked on tile %v: %v", t, r)
+		}
+	}()
+	return work(ctx, t)
+}
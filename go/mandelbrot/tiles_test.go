@@ -0,0 +1,81 @@
+package mandelbrot
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestTilesCoverImage(t *testing.T) {
+	for _, size := range testSizes {
+		width, height := size[0], size[1]
+		for _, tileSize := range []int{1, 5, TileSize, 500} {
+			covered := make([]int, width*height)
+			for _, tile := range Tiles(width, height, tileSize) {
+				if tile.Width() <= 0 || tile.Height() <= 0 || tile.Width() > tileSize || tile.Height() > tileSize {
+					t.Fatalf("%dx%d, size %d: bad tile %+v", width, height, tileSize, tile)
+				}
+				for y := tile.Y0; y < tile.Y1; y++ {
+					for x := tile.X0; x < tile.X1; x++ {
+						covered[y*width+x]++
+					}
+				}
+			}
+			for i, n := range covered {
+				if n != 1 {
+					t.Fatalf("%dx%d, size %d: pixel (%d, %d) covered %d times", width, height, tileSize, i%width, i/width, n)
+				}
+			}
+		}
+	}
+}
+
+func TestRunTilesReturnsFirstError(t *testing.T) {
+	failure := errors.New("failure")
+	tiles := Tiles(1000, 1000, 10)
+	var done atomic.Int64
+	err := runTiles(context.Background(), tiles, 4, func(_ context.Context, tile Tile) error {
+		if tile.X0 == 500 && tile.Y0 == 0 {
+			return failure
+		}
+		done.Add(1)
+		return nil
+	})
+	if err != failure {
+		t.Fatalf("got error %v, want %v", err, failure)
+	}
+	if int(done.Load()) >= len(tiles)-1 {
+		t.Errorf("the error did not cancel the remaining tiles")
+	}
+}
+
+func TestRunTilesRecoversPanics(t *testing.T) {
+	err := runTiles(context.Background(), Tiles(100, 100, 10), 3, func(_ context.Context, tile Tile) error {
+		if tile.X0 == 50 {
+			panic("boom")
+		}
+		return nil
+	})
+	if err == nil || !strings.Contains(err.Error(), "boom") {
+		t.Fatalf("got error %v, want the panic", err)
+	}
+}
+
+func TestComputeReportsFormulaPanics(t *testing.T) {
+	m := NewFractal(64, 64, panickingFormula{})
+	if _, err := Compute(context.Background(), m, 2, 100); err == nil {
+		t.Fatal("Compute succeeded with a panicking formula")
+	}
+	if _, _, err := Render(context.Background(), m, 2, 100); err == nil {
+		t.Fatal("Render succeeded with a panicking formula")
+	}
+}
+
+// panickingFormula is a broken Formula, standing for any bug in a worker.
+type panickingFormula struct{ Quadratic }
+
+func (panickingFormula) Iterate(z, c complex128) complex128 {
+	panic("broken formula")
+}
@@ -0,0 +1,122 @@
+package mandelbrot
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// View frames an image by its center, its width in the complex plane and a rotation.
+// Unlike the XMin/XMax/YMin/YMax corners, it cannot stretch the image: the height of
+// the view always follows the ratio of the image, so pixels stay square.
+type View struct {
+	CenterX, CenterY float64
+	// Scale is the width of the image in the complex plane.
+	Scale float64
+	// Angle rotates the view counterclockwise around its center, in degrees.
+	Angle float64
+	// Skew shears the view before its rotation, see Mandelbrot.Skew.
+	Skew float64
+}
+
+// ViewFromBounds returns the view centered on the given corners with their width.
+// If the corners do not have the ratio of the image, their height is not kept.
+func ViewFromBounds(xMin, xMax, yMin, yMax float64) View {
+	return View{
+		CenterX: (xMin + xMax) / 2,
+		CenterY: (yMin + yMax) / 2,
+		Scale:   xMax - xMin,
+	}
+}
+
+// ZoomScale returns the scale of a view magnified zoom times from the default view of the formula of m.
+func (m Mandelbrot) ZoomScale(zoom float64) float64 {
+	xMin, xMax, _, _ := m.formula().DefaultView()
+	return (xMax - xMin) / zoom
+}
+
+// Bounds returns the corners of the view, before rotation, for an image of width*height pixels.
+func (v View) Bounds(width, height int) (xMin, xMax, yMin, yMax float64) {
+	halfWidth := v.Scale / 2
+	halfHeight := v.Scale * float64(height) / float64(width) / 2
+	return v.CenterX - halfWidth, v.CenterX + halfWidth, v.CenterY - halfHeight, v.CenterY + halfHeight
+}
+
+// SetView frames m with v, leaving its deep zoom if any.
+func (m *Mandelbrot) SetView(v View) {
+	m.Deep = nil
+	m.XMin, m.XMax, m.YMin, m.YMax = v.Bounds(m.Width, m.Height)
+	m.Angle = v.Angle
+	m.Skew = v.Skew
+}
+
+// SetCenter frames m on the decimal center "x,y" with the decimal width scale, keeping
+// its angle and skew. A non-zero precision makes it a deep zoom with numbers of this
+// mantissa size, iterated exactly instead of by perturbation when exact is set.
+func (m *Mandelbrot) SetCenter(center, scale string, precision uint, exact bool) error {
+	centerX, centerY, found := strings.Cut(center, ",")
+	if !found {
+		return fmt.Errorf("invalid center, expected x,y: %s", center)
+	}
+	if scale == "" {
+		return fmt.Errorf("center needs a scale or a zoom")
+	}
+
+	if precision != 0 {
+		deep, err := NewDeepZoom(centerX, centerY, scale, precision)
+		if err != nil {
+			return err
+		}
+		deep.Exact = exact
+		m.SetDeepZoom(deep)
+		return nil
+	}
+
+	view := View{Angle: m.Angle, Skew: m.Skew}
+	var err error
+	if view.CenterX, err = strconv.ParseFloat(centerX, 64); err != nil {
+		return fmt.Errorf("invalid center x: %s", centerX)
+	}
+	if view.CenterY, err = strconv.ParseFloat(centerY, 64); err != nil {
+		return fmt.Errorf("invalid center y: %s", centerY)
+	}
+	if view.Scale, err = strconv.ParseFloat(scale, 64); err != nil || view.Scale <= 0 {
+		return fmt.Errorf("invalid scale: %s", scale)
+	}
+	m.SetView(view)
+	return nil
+}
+
+// View returns the view of m, see ViewFromBounds.
+func (m Mandelbrot) View() View {
+	v := ViewFromBounds(m.XMin, m.XMax, m.YMin, m.YMax)
+	v.Angle = m.Angle
+	v.Skew = m.Skew
+	return v
+}
+
+// Point returns the point of the complex plane at the pixel coordinates (x, y).
+func (m Mandelbrot) Point(x, y float64) complex128 {
+	p := complex(
+		x/float64(m.Width)*(m.XMax-m.XMin)+m.XMin,
+		y/float64(m.Height)*(m.YMax-m.YMin)+m.YMin,
+	)
+	if m.Angle == 0 && m.Skew == 0 {
+		return p
+	}
+	// skews and rotates p around the center of the view
+	center := complex((m.XMin+m.XMax)/2, (m.YMin+m.YMax)/2)
+	u, v := m.transform(real(p-center), imag(p-center))
+	return complex(u, v) + center
+}
+
+// transform applies the skew then the rotation of m to the offset (u, v) from the center of the view.
+func (m Mandelbrot) transform(u, v float64) (float64, float64) {
+	u += m.Skew * v
+	if m.Angle == 0 {
+		return u, v
+	}
+	sin, cos := math.Sincos(m.Angle * math.Pi / 180)
+	return u*cos - v*sin, u*sin + v*cos
+}
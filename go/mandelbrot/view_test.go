@@ -0,0 +1,79 @@
+package mandelbrot
+
+import (
+	"context"
+	"testing"
+)
+
+func TestSetCenter(t *testing.T) {
+	m := NewMandelbrot(200, 100)
+	m.Angle = 30
+	if err := m.SetCenter("-0.75,0.5", "2", 0, false); err != nil {
+		t.Fatal(err)
+	}
+	want := View{CenterX: -0.75, CenterY: 0.5, Scale: 2, Angle: 30}
+	if v := m.View(); v != want {
+		t.Errorf("SetCenter framed %+v, want %+v", v, want)
+	}
+	if m.YMax-m.YMin != 1 {
+		t.Errorf("the view is %v high, want 1", m.YMax-m.YMin)
+	}
+
+	if err := m.SetCenter("-0.75,0.1", "1e-30", 128, true); err != nil {
+		t.Fatal(err)
+	}
+	if m.Deep == nil || !m.Deep.Exact || m.Deep.Precision != 128 || m.Deep.Scale.Text('g', -1) != "1e-30" {
+		t.Errorf("SetCenter set the deep zoom %+v, want an exact 128 bits zoom of scale 1e-30", m.Deep)
+	}
+}
+
+func TestSetCenterErrors(t *testing.T) {
+	tests := []struct {
+		center, scale string
+		precision     uint
+	}{
+		{"-0.75", "2", 0},
+		{"x,0", "2", 0},
+		{"0,y", "2", 0},
+		{"0,0", "", 0},
+		{"0,0", "-1", 0},
+		{"0,0", "zero", 0},
+		{"0,0", "0", 128},
+		{"x,0", "1e-30", 128},
+	}
+	for _, test := range tests {
+		m := NewMandelbrot(10, 10)
+		if err := m.SetCenter(test.center, test.scale, test.precision, false); err == nil {
+			t.Errorf("SetCenter(%q, %q, %d) succeeded, want an error", test.center, test.scale, test.precision)
+		}
+	}
+}
+
+// TestSetCenterLeavesDeepZoom frames a deep zoom, then a float64 view.
+func TestSetCenterLeavesDeepZoom(t *testing.T) {
+	m := NewMandelbrot(16, 16)
+	if err := m.SetCenter("-0.75,0.1", "1e-30", 128, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.SetCenter("0,0", "4", 0, false); err != nil {
+		t.Fatal(err)
+	}
+	if m.Deep != nil {
+		t.Fatalf("the deep zoom %+v is still set", m.Deep)
+	}
+	got, err := Compute(context.Background(), m, 1, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain := NewMandelbrot(16, 16)
+	plain.SetView(View{Scale: 4})
+	want, err := Compute(context.Background(), plain, 1, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, e := range want.Values {
+		if got.Values[i] != e {
+			t.Fatalf("pixel %d escapes as %+v, want %+v", i, got.Values[i], e)
+		}
+	}
+}
@@ -2,8 +2,12 @@ package main
 
 import (
 	"bufio"
+	"bytes"
+	"context"
 	"encoding/base64"
+	"errors"
 	"fmt"
+	"image"
 
 	"log"
 	. "mandelbrot/mandelbrot"
@@ -12,6 +16,19 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"time"
+)
+
+// progressInterval is the least time between two PROGRESS messages sent to a client.
+const progressInterval = 250 * time.Millisecond
+
+// renderTimeout is the longest a client can wait for an image, the render is cancelled after it.
+const renderTimeout = 2 * time.Minute
+
+// Size and number of iterations of the images sent to the clients (variables so that tests can shrink them).
+var (
+	imageWidth, imageHeight = 1000, 1000
+	nbIteration             = 1000
 )
 
 func main() {
@@ -82,10 +99,10 @@ func handleConnection(conn net.Conn, wg *sync.WaitGroup) {
 			// if the user sent "end", the server disconnects the client
 		} else if command == "send image" {
 			// Collect parameters
-			var xmin, xmax, ymin, ymax float32
+			var xmin, xmax, ymin, ymax float64
 
-			// Helper inline function to read and parse a float32 value
-			readFloat := func(prompt string) (float32, error) {
+			// Helper inline function to read and parse a float64 value
+			readFloat := func(prompt string) (float64, error) {
 				writer.WriteString(prompt)
 				writer.Flush()
 				input, err := reader.ReadString('\n')
@@ -93,11 +110,11 @@ func handleConnection(conn net.Conn, wg *sync.WaitGroup) {
 					return 0, err
 				}
 				input = strings.TrimSpace(input)
-				val, err := strconv.ParseFloat(input, 32) //converts to float32 precision but still returns float64
+				val, err := strconv.ParseFloat(input, 64) //keeps the full float64 precision for zooms
 				if err != nil {
 					return 0, fmt.Errorf("invalid float value: %s", input)
 				}
-				return float32(val), nil //converts to float64
+				return val, nil
 			}
 
 			// Read and parse each parameter
@@ -129,32 +146,89 @@ func handleConnection(conn net.Conn, wg *sync.WaitGroup) {
 				continue
 			}
 
-			// Call the mandelbrot function
-			writer.WriteString(fmt.Sprintf("generating mandelbrot with xmin=%.2f, xmax=%.2f, ymin=%.2f, ymax=%.2f\n", xmin, xmax, ymin, ymax))
+			writer.WriteString("Enter options (e.g. formula=burningship palette=fire center=-0.75,0.1 zoom=100 angle=30 progressive=true, empty for defaults): \n")
 			writer.Flush()
+			options, err := reader.ReadString('\n')
+			if err != nil {
+				fmt.Print("Error reading from client:", err)
+				return
+			}
 
 			// Define image dimensions
-			const width, height = 1000, 1000
-			const numGoRoutines = 100
-			const nbIteration = 1000
+			width, height := imageWidth, imageHeight
+			const numWorkers = 0 // one worker per CPU
 
 			mandelbrot := NewMandelbrot(width, height)
-			mandelbrot.XMin = float64(xmin)
-			mandelbrot.XMax = float64(xmax)
-			mandelbrot.YMin = float64(ymin)
-			mandelbrot.YMax = float64(ymax)
+			mandelbrot.XMin = xmin
+			mandelbrot.XMax = xmax
+			mandelbrot.YMin = ymin
+			mandelbrot.YMax = ymax
+			request, err := applyOptions(&mandelbrot, options)
+			if err != nil {
+				writer.WriteString(fmt.Sprintf("Invalid options: %v. Please try again.\n", err))
+				writer.Flush()
+				continue
+			}
 
-			fileName := "Mandelbrot.png"
-			err := PrintOnImage(mandelbrot, fileName, numGoRoutines, nbIteration)
+			// Call the mandelbrot function
+			// the options may have replaced the bounds typed by the client
+			writer.WriteString(fmt.Sprintf("generating mandelbrot with xmin=%g, xmax=%g, ymin=%g, ymax=%g\n", mandelbrot.XMin, mandelbrot.XMax, mandelbrot.YMin, mandelbrot.YMax))
+			writer.Flush()
 
-			if err != nil {
-				fmt.Print("Error generating Mandelbrot image:", err)
+			// tells the client how far the render went, a few times per second
+			var lastProgress time.Time
+			mandelbrot.OnProgress = func(p Progress) {
+				if p.Done < p.Total && time.Since(lastProgress) < progressInterval {
+					return
+				}
+				lastProgress = time.Now()
+				writer.WriteString(fmt.Sprintf("PROGRESS:%v\n", p))
+				writer.Flush()
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), renderTimeout)
+			stopWatching := watchDisconnect(conn, reader, cancel)
+			var img *image.RGBA
+			var stats RenderStats
+			if request.progressive {
+				// sends the image of every pass but the last one, which is sent like a normal image
+				img, stats, err = RenderProgressive(ctx, mandelbrot, numWorkers, nbIteration, func(p Pass, preview *image.RGBA) error {
+					if p.Number == p.Passes {
+						return nil
+					}
+					writer.WriteString(fmt.Sprintf("PREVIEW:%d/%d\n", p.Number, p.Passes))
+					return sendImage(writer, preview)
+				})
+			} else {
+				img, stats, err = Render(ctx, mandelbrot, numWorkers, nbIteration)
+			}
+			disconnected := stopWatching()
+			cancel()
+
+			if disconnected {
+				fmt.Println("Client disconnected during the render, render cancelled.")
 				return
 			}
+			if errors.Is(err, context.DeadlineExceeded) {
+				fmt.Println("Render timed out:", err)
+				writer.WriteString(fmt.Sprintf("The image took more than %v to render and was cancelled. Please try a smaller zoom or fewer samples.\n", renderTimeout))
+				writer.Flush()
+				continue
+			}
+			if err != nil {
+				// the client is told about the failure and can ask for another image
+				fmt.Println("Error generating Mandelbrot image:", err)
+				writer.WriteString(fmt.Sprintf("Error generating Mandelbrot image: %v. Please try again.\n", err))
+				writer.Flush()
+				continue
+			}
+			if mandelbrot.Samples > 1 {
+				writer.WriteString(fmt.Sprintf("Supersampled %d of %d pixels.\n", stats.Refined, width*height))
+			}
 
 			writer.WriteString("Image generation triggered successfully.\n")
 			writer.Flush()
-			err = sendImage(writer)
+			err = sendImage(writer, img)
 			if err != nil {
 				fmt.Print("Error sending image:", err)
 				return
@@ -167,17 +241,205 @@ func handleConnection(conn net.Conn, wg *sync.WaitGroup) {
 	}
 }
 
-func sendImage(writer *bufio.Writer) error {
-	// Read the image file
-	imageData, err := os.ReadFile("Mandelbrot.png") //reads the imagefile into imageData
-	if err != nil {
-		return fmt.Errorf("failed to read image file: %w", err)
+// watchDisconnect cancels the render of conn with cancel if the client disconnects while it is running.
+// The returned function stops watching, leaving reader ready for the next command,
+// and tells whether the client disconnected.
+func watchDisconnect(conn net.Conn, reader *bufio.Reader, cancel context.CancelFunc) func() bool {
+	done := make(chan bool)
+	go func() {
+		// blocks until the client sends something or the connection is lost
+		_, err := reader.Peek(1)
+		lost := err != nil && !errors.Is(err, os.ErrDeadlineExceeded)
+		if lost {
+			cancel()
+		}
+		done <- lost
+	}()
+	return func() bool {
+		// makes Peek return, without losing what the client may already have sent
+		conn.SetReadDeadline(time.Now())
+		lost := <-done
+		conn.SetReadDeadline(time.Time{})
+		return lost
+	}
+}
+
+// requestOptions are the options of a request which do not describe the image itself.
+type requestOptions struct {
+	// progressive asks for a preview image after each pass of the render (see RenderProgressive).
+	progressive bool
+}
+
+// applyOptions parses a line of space separated key=value options, applies them to m
+// and returns the ones about the request.
+// Supported keys:
+//
+//	julia=<c>        renders the Julia set of the complex constant c (e.g. -0.8+0.156i)
+//	formula=<name>   iterates the named formula (see FormulaByName), e.g. burningship or multibrot:3
+//	kernel=<name>    escape time loop: reference (default, float64), fast, batch, float32 or fixed
+//	strategy=<name>  pixels to iterate: bruteforce (default) or subdivision
+//	coloring=<mode>  banded (default) or smooth
+//	palette=<name>   built-in palette: classic (default), grayscale, fire or ultra
+//	samples=<n>      supersamples every pixel with n x n samples
+//	sampling=<mode>  grid (default) or jittered placement of the samples
+//	adaptive=true    only supersamples the pixels on an edge of the escape values
+//	threshold=<v>    escape value difference between neighbors that makes an edge, 0 for any difference
+//	center=<x>,<y>   center of the view, replacing the bounds (needs scale or zoom)
+//	scale=<width>    width of the view in the complex plane, the height follows the image ratio
+//	zoom=<factor>    magnification from the default view of the formula, instead of scale
+//	angle=<degrees>  rotation of the view around its center
+//	skew=<factor>    horizontal shear of the view, applied before the rotation
+//	precision=<bits> renders a deep zoom with numbers of this mantissa size
+//	exact=true       iterates every pixel of the deep zoom in arbitrary precision instead of by perturbation
+//	progressive=true sends a preview after each pass of the render, not with strategy=subdivision
+func applyOptions(m *Mandelbrot, line string) (requestOptions, error) {
+	var request requestOptions
+	var center, scale, zoom string
+	var precision uint64
+	var exact bool
+	for _, option := range strings.Fields(line) {
+		key, value, found := strings.Cut(option, "=")
+		if !found {
+			return request, fmt.Errorf("expected key=value, got %q", option)
+		}
+		switch key {
+		case "julia":
+			c, err := strconv.ParseComplex(value, 128)
+			if err != nil {
+				return request, fmt.Errorf("invalid julia constant: %s", value)
+			}
+			m.Julia = true
+			m.JuliaC = c
+		case "formula":
+			f, err := FormulaByName(value)
+			if err != nil {
+				return request, err
+			}
+			m.Formula = f
+		case "kernel":
+			kernel, err := KernelByName(value)
+			if err != nil {
+				return request, err
+			}
+			m.Kernel = kernel
+		case "strategy":
+			strategy, err := StrategyByName(value)
+			if err != nil {
+				return request, err
+			}
+			m.Strategy = strategy
+		case "coloring":
+			coloring, err := ColoringByName(value)
+			if err != nil {
+				return request, err
+			}
+			m.Coloring = coloring
+		case "palette":
+			palette, err := PaletteByName(value)
+			if err != nil {
+				return request, err
+			}
+			m.Palette = palette
+		case "samples":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return request, fmt.Errorf("invalid samples: %s", value)
+			}
+			m.Samples = n
+		case "sampling":
+			sampling, err := SamplingByName(value)
+			if err != nil {
+				return request, err
+			}
+			m.Sampling = sampling
+		case "adaptive":
+			adaptive, err := strconv.ParseBool(value)
+			if err != nil {
+				return request, fmt.Errorf("invalid adaptive: %s", value)
+			}
+			m.Adaptive = adaptive
+		case "threshold":
+			threshold, err := strconv.ParseFloat(value, 64)
+			if err != nil || threshold < 0 {
+				return request, fmt.Errorf("invalid threshold: %s", value)
+			}
+			m.AdaptiveThreshold = threshold
+		case "center":
+			center = value
+		case "scale":
+			scale = value
+		case "zoom":
+			zoom = value
+		case "angle":
+			angle, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return request, fmt.Errorf("invalid angle: %s", value)
+			}
+			m.Angle = angle
+		case "skew":
+			skew, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return request, fmt.Errorf("invalid skew: %s", value)
+			}
+			m.Skew = skew
+		case "precision":
+			bits, err := strconv.ParseUint(value, 10, 32)
+			if err != nil {
+				return request, fmt.Errorf("invalid precision: %s", value)
+			}
+			precision = bits
+		case "progressive":
+			progressive, err := strconv.ParseBool(value)
+			if err != nil {
+				return request, fmt.Errorf("invalid progressive: %s", value)
+			}
+			request.progressive = progressive
+		case "exact":
+			b, err := strconv.ParseBool(value)
+			if err != nil {
+				return request, fmt.Errorf("invalid exact: %s", value)
+			}
+			exact = b
+		default:
+			return request, fmt.Errorf("unknown option %q", key)
+		}
 	}
 
-	base64Data := base64.StdEncoding.EncodeToString(imageData) //converts image into a base64 string which is easier to transmit using tcp
+	if center == "" {
+		if scale != "" || zoom != "" || precision != 0 {
+			return request, fmt.Errorf("scale, zoom and precision need center=<x>,<y>")
+		}
+		return request, nil
+	}
+	return request, applyView(m, center, scale, zoom, uint(precision), exact)
+}
+
+// applyView frames m with the center=<x>,<y> option and its scale or zoom,
+// as a deep zoom when a precision is given.
+func applyView(m *Mandelbrot, center, scale, zoom string, precision uint, exact bool) error {
+	if zoom != "" {
+		factor, err := strconv.ParseFloat(zoom, 64)
+		if err != nil || factor <= 0 {
+			return fmt.Errorf("invalid zoom: %s", zoom)
+		}
+		scale = strconv.FormatFloat(m.ZoomScale(factor), 'g', -1, 64)
+	}
+	return m.SetCenter(center, scale, precision, exact)
+}
+
+// sendImage sends img to the client as a base64 encoded PNG, announced by its size.
+// The IMAGE_SIZE line comes before the data, so the PNG is encoded in memory
+// instead of being streamed with RenderTo.
+func sendImage(writer *bufio.Writer, img image.Image) error {
+	// Encode the image in memory, the client needs its size before the data
+	var imageData bytes.Buffer
+	err := WriteImage(&imageData, img)
+	if err != nil {
+		return err
+	}
 
 	// Send the image size first
-	sizeMsg := fmt.Sprintf("IMAGE_SIZE:%d\n", len(base64Data))
+	sizeMsg := fmt.Sprintf("IMAGE_SIZE:%d\n", base64.StdEncoding.EncodedLen(imageData.Len()))
 	_, err = writer.WriteString(sizeMsg)
 	if err != nil {
 		return fmt.Errorf("failed to send size: %w", err)
@@ -191,19 +453,14 @@ func sendImage(writer *bufio.Writer) error {
 	}
 	writer.Flush()
 
-	// Send the base64 data in chunks
-	chunkSize := 1024
-	for i := 0; i < len(base64Data); i += chunkSize {
-		end := i + chunkSize
-		if end > len(base64Data) {
-			end = len(base64Data)
-		}
-
-		_, err := writer.WriteString(base64Data[i:end])
-		if err != nil {
-			return fmt.Errorf("failed to send data chunk: %w", err)
-		}
-		writer.Flush()
+	// Stream the base64 data to the client, converted as it is sent (easier to transmit using tcp)
+	encoder := base64.NewEncoder(base64.StdEncoding, writer)
+	_, err = imageData.WriteTo(encoder)
+	if err == nil {
+		err = encoder.Close() // writes the last partial block
+	}
+	if err != nil {
+		return fmt.Errorf("failed to send image data: %w", err)
 	}
 
 	// Send end marker
@@ -211,13 +468,5 @@ func sendImage(writer *bufio.Writer) error {
 	if err != nil {
 		return fmt.Errorf("failed to send end marker: %w", err)
 	}
-	writer.Flush()
-
-	// Delete the image file
-	err = os.Remove("Mandelbrot.png")
-	if err != nil {
-		return fmt.Errorf("failed to delete image file: %w", err)
-	}
-
-	return nil
+	return writer.Flush()
 }
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	. "mandelbrot/mandelbrot"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// setImageSize shrinks the images rendered by the server for the duration of the test.
+func setImageSize(t *testing.T, width, height, iterations int) {
+	oldWidth, oldHeight, oldIterations := imageWidth, imageHeight, nbIteration
+	imageWidth, imageHeight, nbIteration = width, height, iterations
+	t.Cleanup(func() {
+		imageWidth, imageHeight, nbIteration = oldWidth, oldHeight, oldIterations
+	})
+}
+
+// startServer serves the clients on a free local port and returns its address.
+func startServer(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			wg.Add(1)
+			go handleConnection(conn, &wg)
+		}
+	}()
+	t.Cleanup(func() {
+		listener.Close()
+		wg.Wait()
+	})
+	return listener.Addr().String()
+}
+
+// requestImage asks the server at addr for the image of the given bounds, like the client does.
+// It returns the final image and the previews sent before it.
+func requestImage(addr string, xmin, xmax, ymin, ymax float64, options string) (image.Image, []image.Image, error) {
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+
+	request := fmt.Sprintf("send image\n%v\n%v\n%v\n%v\n%s\n", xmin, xmax, ymin, ymax, options)
+	if _, err := conn.Write([]byte(request)); err != nil {
+		return nil, nil, err
+	}
+
+	var previews []image.Image
+	preview := false
+	for {
+		message, err := reader.ReadString('\n')
+		if err != nil {
+			return nil, nil, err
+		}
+		message = strings.TrimSpace(message)
+		if strings.HasPrefix(message, "Error") || strings.HasPrefix(message, "Invalid") {
+			return nil, nil, fmt.Errorf("server replied %q", message)
+		}
+		if strings.HasPrefix(message, "PREVIEW:") {
+			preview = true
+		}
+		if !strings.HasPrefix(message, "IMAGE_SIZE:") {
+			continue
+		}
+		size, err := strconv.Atoi(strings.TrimPrefix(message, "IMAGE_SIZE:"))
+		if err != nil {
+			return nil, nil, err
+		}
+		img, err := receiveImage(reader, size)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !preview {
+			conn.Write([]byte("end\n"))
+			return img, previews, nil
+		}
+		previews = append(previews, img)
+		preview = false
+	}
+}
+
+// receiveImage reads an image of size base64 bytes sent by sendImage.
+func receiveImage(reader *bufio.Reader, size int) (image.Image, error) {
+	lines := make([]string, 3)
+	for i := range lines {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		lines[i] = strings.TrimSpace(line)
+	}
+	if lines[0] != "START_IMAGE" || len(lines[1]) != size || lines[2] != "END_IMAGE" {
+		return nil, fmt.Errorf("malformed image: %q, %d bytes of %d, %q", lines[0], len(lines[1]), size, lines[2])
+	}
+	data, err := base64.StdEncoding.DecodeString(lines[1])
+	if err != nil {
+		return nil, err
+	}
+	return png.Decode(strings.NewReader(string(data)))
+}
+
+func TestConcurrentImageRequests(t *testing.T) {
+	setImageSize(t, 64, 48, 200)
+	addr := startServer(t)
+
+	const clients = 24
+	images := make([]image.Image, clients)
+	errs := make([]error, clients)
+	var wg sync.WaitGroup
+	for i := 0; i < clients; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// every client looks at its own part of the set
+			x := -2 + 0.1*float64(i)
+			images[i], _, errs[i] = requestImage(addr, x, x+0.5, -0.5, 0.5, "")
+		}()
+	}
+	wg.Wait()
+
+	for i := 0; i < clients; i++ {
+		if errs[i] != nil {
+			t.Errorf("client %d: %v", i, errs[i])
+			continue
+		}
+		x := -2 + 0.1*float64(i)
+		m := NewMandelbrot(imageWidth, imageHeight)
+		m.XMin, m.XMax, m.YMin, m.YMax = x, x+0.5, -0.5, 0.5
+		want, _, err := Render(context.Background(), m, 1, nbIteration)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !sameImage(images[i], want) {
+			t.Errorf("client %d did not get the image of its coordinates", i)
+		}
+	}
+}
+
+// sameImage tells whether got has exactly the pixels of want.
+func sameImage(got image.Image, want *image.RGBA) bool {
+	if got.Bounds() != want.Bounds() {
+		return false
+	}
+	for y := want.Rect.Min.Y; y < want.Rect.Max.Y; y++ {
+		for x := want.Rect.Min.X; x < want.Rect.Max.X; x++ {
+			if color.RGBAModel.Convert(got.At(x, y)) != want.RGBAAt(x, y) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func TestProgressiveImageRequest(t *testing.T) {
+	setImageSize(t, 64, 48, 200)
+	addr := startServer(t)
+
+	img, previews, err := requestImage(addr, -2, 1, -1, 1, "progressive=true")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(previews) != 3 {
+		t.Errorf("got %d previews, want 3", len(previews))
+	}
+	m := NewMandelbrot(imageWidth, imageHeight)
+	m.XMin, m.XMax, m.YMin, m.YMax = -2, 1, -1, 1
+	want, _, err := Render(context.Background(), m, 1, nbIteration)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !sameImage(img, want) {
+		t.Error("the last image of a progressive request differs from the normal one")
+	}
+	if len(previews) > 0 && sameImage(previews[0], want) {
+		t.Error("the first preview is already the final image")
+	}
+}
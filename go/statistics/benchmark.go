@@ -4,10 +4,12 @@ import (
 	"encoding/csv"
 	"fmt"
 	"image/png"
+	. "mandelbrot/mandelbrot"
 	"math"
 	"os"
 	"os/exec"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -22,16 +24,25 @@ const (
 	MaxIterations = 3000 // Maximum number of iterations
 	IterationStep = 20   // Step size for iterations
 
-	MinGoroutines = 1    // Minimum number of goroutines
-	MaxGoroutines = 2161 // Maximum number of goroutines
-	GoroutineStep = 1    // Step size for goroutines
+	MinSamples = 1 // Minimum number of samples per pixel side
+	MaxSamples = 1 // Maximum number of samples per pixel side
+	SampleStep = 1 // Step size for samples
 
 	NbAvgTest = 5 // Number of times to run the test and average the results
+
+	// CompareRawBuffers compares escape buffers instead of decoded PNGs,
+	// the quality is then the percentage of misclassified pixels
+	CompareRawBuffers = false
+	PerfectBuffer     = "Perfect_Mandelbrot.mbe"
+	PerfectIterations = 10000 // Number of iterations of the reference buffer
 )
 
-func isDivisor(n int) bool {
-	return 2160%n == 0
-}
+// Goroutines are the worker pool sizes swept: any size works with tiles, a few of them
+// per order of magnitude keep the sweep about as long as with the 40 divisors of 2160
+var Goroutines = []int{1, 2, 3, 4, 6, 8, 12, 16, 24, 32, 64, 128, 256, 512, 1024, 2160}
+
+// Kernels are the precisions swept, by the name of their kernel (see KernelByName)
+var Kernels = []string{"float32", "float64", "fixed"}
 
 func compareImages(fileName, perfectImage string) (float64, error) {
 	// Open the generated image
@@ -89,6 +100,45 @@ func compareImages(fileName, perfectImage string) (float64, error) {
 	return totalDifference / numPixels, nil
 }
 
+// compareBuffers returns the percentage of pixels which are not classified the same way
+// (inside or outside the set) in the generated and the perfect escape buffers.
+func compareBuffers(fileName, perfectBuffer string) (float64, error) {
+	genBuffer, err := LoadEscapeBuffer(fileName)
+	if err != nil {
+		return 0, fmt.Errorf("error loading generated buffer: %v", err)
+	}
+	perfBuffer, err := LoadEscapeBuffer(perfectBuffer)
+	if err != nil {
+		return 0, fmt.Errorf("error loading perfect buffer: %v", err)
+	}
+
+	// Ensure dimensions match
+	if genBuffer.Width != perfBuffer.Width || genBuffer.Height != perfBuffer.Height {
+		return 0, fmt.Errorf("buffer dimensions do not match")
+	}
+
+	misclassified := 0
+	for i := range genBuffer.Values {
+		if genBuffer.Inside(genBuffer.Values[i]) != perfBuffer.Inside(perfBuffer.Values[i]) {
+			misclassified++
+		}
+	}
+
+	return 100 * float64(misclassified) / float64(len(genBuffer.Values)), nil
+}
+
+// generatePerfectBuffer computes the reference escape buffer with PerfectIterations iterations.
+func generatePerfectBuffer() error {
+	cmd := exec.Command("go", "run", "main.go", "1", strconv.Itoa(PerfectIterations), "1", "raw", "float64")
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("error running Mandelbrot program: %v\nOutput: %s", err, string(output))
+	}
+	fileName := fmt.Sprintf("Mandelbrot_%dx%d_%dIterations_%dGoroutines_%dSamples_%s", ImageWidth, ImageHeight, PerfectIterations, 1, 1, "float64")
+	os.Remove(fileName + ".png")
+	return os.Rename(fileName+".mbe", PerfectBuffer)
+}
+
 func main() {
 	// Open the CSV file for writing
 	file, err := os.Create(OutputFile)
@@ -102,8 +152,18 @@ func main() {
 	writer.Comma = ';' // Set the delimiter to semicolon
 	defer writer.Flush()
 
+	if CompareRawBuffers {
+		if _, err := os.Stat(PerfectBuffer); os.IsNotExist(err) {
+			fmt.Println("Generating", PerfectBuffer)
+			if err := generatePerfectBuffer(); err != nil {
+				fmt.Printf("Error generating perfect buffer: %v\n", err)
+				return
+			}
+		}
+	}
+
 	// Write the header row to the CSV file
-	err = writer.Write([]string{"Iterations", "Goroutines", "ExecutionTime(ms)", "Quality"})
+	err = writer.Write([]string{"Iterations", "Goroutines", "ExecutionTime(ms)", "Quality", "Samples", "Kernel"})
 	if err != nil {
 		fmt.Printf("Error writing header to CSV: %v\n", err)
 		return
@@ -111,58 +171,65 @@ func main() {
 
 	// Sweep through the parameter space
 	for iterations := MinIterations; iterations <= MaxIterations; iterations += IterationStep {
-		for goroutines := MinGoroutines; goroutines <= MaxGoroutines; goroutines += GoroutineStep {
-			if !isDivisor(goroutines) {
-				fmt.Printf("Skipping non-divisor number of goroutines: %d\n", goroutines)
-				continue
-			}
-
-			var totalExecutionTime int64
-			var totalScore float64
-
-			// Run the test 10 times and average the results
-			for i := 0; i < NbAvgTest; i++ {
-				start := time.Now()
-				fileName := fmt.Sprintf("Mandelbrot_%dx%d_%dIterations_%dGoroutines.png", ImageWidth, ImageHeight, iterations, goroutines)
-				cmd := exec.Command("go", "run", "main.go",
-					strconv.Itoa(goroutines),
-					strconv.Itoa(iterations),
-				)
-				output, err := cmd.CombinedOutput()
-				if err != nil {
-					fmt.Printf("Error running Mandelbrot program: %v\nOutput: %s\n", err, string(output))
-					continue
-				}
-				executionTime := time.Since(start).Milliseconds()
-				totalExecutionTime += executionTime
-
-				// Compare the generated image with the "perfect" image and calculate SSIM
-				score, err := compareImages(fileName, "Perfect_Mandelbrot.png")
-				if err != nil {
-					fmt.Println("Error comparing images:", err)
-					continue
+		for _, goroutines := range Goroutines {
+			for _, kernel := range Kernels {
+				for samples := MinSamples; samples <= MaxSamples; samples += SampleStep {
+					var totalExecutionTime int64
+					var totalScore float64
+
+					// Run the test 10 times and average the results
+					for i := 0; i < NbAvgTest; i++ {
+						start := time.Now()
+						fileName := fmt.Sprintf("Mandelbrot_%dx%d_%dIterations_%dGoroutines_%dSamples_%s.png", ImageWidth, ImageHeight, iterations, goroutines, samples, kernel)
+						args := []string{"run", "main.go", strconv.Itoa(goroutines), strconv.Itoa(iterations), strconv.Itoa(samples), kernel}
+						if CompareRawBuffers {
+							args = append(args, "raw")
+						}
+						cmd := exec.Command("go", args...)
+						output, err := cmd.CombinedOutput()
+						if err != nil {
+							fmt.Printf("Error running Mandelbrot program: %v\nOutput: %s\n", err, string(output))
+							continue
+						}
+						executionTime := time.Since(start).Milliseconds()
+						totalExecutionTime += executionTime
+
+						// Compare the generated image with the "perfect" image and calculate SSIM
+						var score float64
+						if CompareRawBuffers {
+							score, err = compareBuffers(strings.TrimSuffix(fileName, ".png")+".mbe", PerfectBuffer)
+						} else {
+							score, err = compareImages(fileName, "Perfect_Mandelbrot.png")
+						}
+						if err != nil {
+							fmt.Println("Error comparing images:", err)
+							continue
+						}
+						totalScore += score
+					}
+
+					// Calculate the average execution time and SSIM
+					avgExecutionTime := totalExecutionTime / NbAvgTest
+					avgScore := totalScore / NbAvgTest
+
+					// Write the result to the CSV file
+					err = writer.Write([]string{
+						strconv.Itoa(iterations),
+						strconv.Itoa(goroutines),
+						strconv.FormatInt(avgExecutionTime, 10),
+						fmt.Sprintf("%.2f", avgScore),
+						strconv.Itoa(samples),
+						kernel,
+					})
+					if err != nil {
+						fmt.Printf("Error writing result to CSV: %v\n", err)
+						return
+					}
+
+					// Print progress to the console
+					fmt.Printf("Completed: Iterations=%d, Goroutines=%d, Samples=%d, Kernel=%s, AvgExecutionTime=%dms, Quality=%.5f\n", iterations, goroutines, samples, kernel, avgExecutionTime, avgScore)
 				}
-				totalScore += score
 			}
-
-			// Calculate the average execution time and SSIM
-			avgExecutionTime := totalExecutionTime / NbAvgTest
-			avgScore := totalScore / NbAvgTest
-
-			// Write the result to the CSV file
-			err = writer.Write([]string{
-				strconv.Itoa(iterations),
-				strconv.Itoa(goroutines),
-				strconv.FormatInt(avgExecutionTime, 10),
-				fmt.Sprintf("%.2f", avgScore),
-			})
-			if err != nil {
-				fmt.Printf("Error writing result to CSV: %v\n", err)
-				return
-			}
-
-			// Print progress to the console
-			fmt.Printf("Completed: Iterations=%d, Goroutines=%d, AvgExecutionTime=%dms, Quality=%.5f\n", iterations, goroutines, avgExecutionTime, avgScore)
 		}
 	}
 
@@ -1,10 +1,12 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	. "mandelbrot/mandelbrot"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -12,7 +14,7 @@ func main() {
 	// Check if sufficient arguments are provided
 	if len(os.Args) < 3 {
 		fmt.Println("Error: Insufficient arguments provided.")
-		fmt.Println("Usage: go run main.go <numGoRoutines> <nbIteration>")
+		fmt.Println("Usage: go run main.go <numGoRoutines> <nbIteration> [samples] [raw] [kernel]")
 		os.Exit(1)
 	}
 
@@ -29,10 +31,38 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Optional arguments : the number of samples per pixel side (1 by default),
+	// "raw" to also save the escape buffer, next to the image,
+	// and the name of the kernel, i.e. the precision of the numbers (float64 by default)
+	samples := 1
+	saveRaw := false
+	kernel := Reference
+	for _, arg := range os.Args[3:] {
+		if arg == "raw" {
+			saveRaw = true
+			continue
+		}
+		if k, err := KernelByName(arg); err == nil {
+			kernel = k
+			continue
+		}
+		samples, err = strconv.Atoi(arg)
+		if err != nil {
+			fmt.Printf("Error parsing samples: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if saveRaw && samples > 1 {
+		fmt.Println("Error: escape buffers hold one sample per pixel, raw needs samples = 1")
+		os.Exit(1)
+	}
+
 	// Define image dimensions
 	const width, height = 3840, 2160
 
 	mandelbrot := NewMandelbrot(width, height)
+	mandelbrot.Samples = samples
+	mandelbrot.Kernel = kernel
 	/*
 	   mandelbrot.XMin = -1
 	   mandelbrot.XMax = 0.5
@@ -41,9 +71,14 @@ func main() {
 	*/
 
 	start := time.Now()
-	fileName := fmt.Sprintf("Mandelbrot_%dx%d_%dIterations_%dGoroutines.png", width, height, nbIteration, numGoRoutines)
-	fmt.Printf("Generating Mandelbrot image with %d goroutines and %d iterations...\n", numGoRoutines, nbIteration)
-	err = PrintOnImage(mandelbrot, fileName, numGoRoutines, nbIteration)
+	precision := kernel.Precision()
+	fileName := fmt.Sprintf("Mandelbrot_%dx%d_%dIterations_%dGoroutines_%dSamples_%s.png", width, height, nbIteration, numGoRoutines, samples, precision)
+	fmt.Printf("Generating Mandelbrot image with %d goroutines, %d iterations, %dx%d samples and the %s kernel...\n", numGoRoutines, nbIteration, samples, samples, precision)
+	if saveRaw {
+		err = printWithBuffer(mandelbrot, fileName, numGoRoutines, nbIteration)
+	} else {
+		err = PrintOnImage(mandelbrot, fileName, numGoRoutines, nbIteration)
+	}
 	elapsed := time.Since(start)
 
 	if err != nil {
@@ -51,5 +86,19 @@ func main() {
 		os.Exit(1)
 	}
 
-	fmt.Printf("Mandelbrot_%dx%d_%dIterations_%dGoroutines.png generated in %v\n", width, height, nbIteration, numGoRoutines, elapsed)
+	fmt.Printf("%s generated in %v\n", fileName, elapsed)
+}
+
+// printWithBuffer renders like PrintOnImage and also saves the escape buffer
+// in a .mbe file named after the image.
+func printWithBuffer(m Mandelbrot, fileName string, numGoRoutines, nbIteration int) error {
+	buffer, err := Compute(context.Background(), m, numGoRoutines, nbIteration)
+	if err != nil {
+		return err
+	}
+	err = SaveImage(Colorize(buffer, ClassicPalette(), m.Coloring), fileName)
+	if err != nil {
+		return err
+	}
+	return SaveEscapeBuffer(buffer, strings.TrimSuffix(fileName, ".png")+".mbe")
 }
@@ -16,12 +16,14 @@ const (
 )
 
 // BenchmarkResult représente une ligne du fichier CSV
-// avec les colonnes : Iterations, Goroutines, ExecutionTimeMs, Quality
+// avec les colonnes : Iterations, Goroutines, ExecutionTimeMs, Quality (puis Samples et Kernel si présentes)
 type BenchmarkResult struct {
 	Iterations      int     // Nombre d'itérations
 	Goroutines      int     // Nombre de goroutines
 	ExecutionTimeMs float64 // Temps d'exécution en millisecondes
 	Quality         float64 // Qualité mesurée
+	Samples         int     // Nombre d'échantillons par côté de pixel
+	Kernel          string  // Noyau de calcul, c'est-à-dire la précision des nombres (float32, float64, fixed)
 }
 
 // LoadCSV charge les résultats de benchmark depuis un fichier CSV
@@ -62,6 +64,16 @@ func LoadCSV(filename string) ([]BenchmarkResult, error) {
 		goroutines, _ := strconv.Atoi(record[1])
 		execTime, _ := strconv.ParseFloat(record[2], 64)
 		quality, _ := strconv.ParseFloat(record[3], 64)
+		// Les anciens fichiers n'ont pas de colonne Samples : un seul échantillon par pixel
+		samples := 1
+		if len(record) > 4 {
+			samples, _ = strconv.Atoi(record[4])
+		}
+		// Ni de colonne Kernel : ils calculaient tous en float64
+		kernel := "float64"
+		if len(record) > 5 {
+			kernel = record[5]
+		}
 
 		// Ignore les lignes avec 1 ou 21 itérations
 		if iterations == 1 || iterations == 21 {
@@ -74,6 +86,8 @@ func LoadCSV(filename string) ([]BenchmarkResult, error) {
 			Goroutines:      goroutines,
 			ExecutionTimeMs: execTime,
 			Quality:         quality,
+			Samples:         samples,
+			Kernel:          kernel,
 		})
 	}
 	return results, nil // Retourne les résultats
@@ -158,6 +172,6 @@ func main() {
 
 	// Affiche la meilleure configuration trouvée
 	fmt.Printf("Best Configuration:\n")
-	fmt.Printf("Iterations: %d, Goroutines: %d\n", bestResult.Iterations, bestResult.Goroutines)
+	fmt.Printf("Iterations: %d, Goroutines: %d, Samples: %d, Kernel: %s\n", bestResult.Iterations, bestResult.Goroutines, bestResult.Samples, bestResult.Kernel)
 	fmt.Printf("Execution Time (ms): %.2f, Quality: %.2f\n", bestResult.ExecutionTimeMs, bestResult.Quality)
 }